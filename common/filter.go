@@ -0,0 +1,196 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package common
+
+import (
+	"encoding/json"
+	"math/big"
+)
+
+// LogFilter holds decoded log filter criteria, as sent to eth_getLogs, and
+// matches logs against them.
+type LogFilter struct {
+	// FromBlock and ToBlock bound the block number, nil means unbounded.
+	FromBlock *big.Int
+	ToBlock   *big.Int
+	// BlockHash restricts the logs to a single block if set.
+	BlockHash *Hash
+	// Addresses are the accepted emitters, empty means any.
+	Addresses []Address
+	// Topics holds the accepted topics by position, an empty position
+	// matches any topic.
+	Topics [][]Hash
+}
+
+// Match tells if a log satisfies every criterion.
+func (f *LogFilter) Match(log *Log) bool {
+	if f.BlockHash != nil && log.BlockHash != *f.BlockHash {
+		return false
+	}
+	if log.BlockNumber != nil {
+		if f.FromBlock != nil && log.BlockNumber.Cmp(f.FromBlock) < 0 {
+			return false
+		}
+		if f.ToBlock != nil && log.BlockNumber.Cmp(f.ToBlock) > 0 {
+			return false
+		}
+	}
+
+	if len(f.Addresses) > 0 {
+		found := false
+		for _, addr := range f.Addresses {
+			if addr == log.Address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.Topics) > len(log.Topics) {
+		return false
+	}
+	for i, alternatives := range f.Topics {
+		if len(alternatives) == 0 {
+			continue
+		}
+		topic := NewHash(log.Topics[i].Data)
+		found := false
+		for _, hash := range alternatives {
+			if hash == topic {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// UnmarshalJSON decodes a filter object. The head block is not known, block
+// tags other than "earliest" leave the range unbounded.
+func (f *LogFilter) UnmarshalJSON(data []byte) error {
+	raw := struct {
+		FromBlock string            `json:"fromBlock"`
+		ToBlock   string            `json:"toBlock"`
+		BlockHash string            `json:"blockHash"`
+		Address   json.RawMessage   `json:"address"`
+		Topics    []json.RawMessage `json:"topics"`
+	}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	result := LogFilter{}
+	var err error
+	if result.FromBlock, err = DecodeBlockBound(raw.FromBlock, nil); err != nil {
+		return err
+	}
+	if result.ToBlock, err = DecodeBlockBound(raw.ToBlock, nil); err != nil {
+		return err
+	}
+	if raw.BlockHash != "" {
+		hash, err := ParseHash(raw.BlockHash)
+		if err != nil {
+			return err
+		}
+		result.BlockHash = &hash
+	}
+
+	addresses, err := decodeOneOrMany(raw.Address)
+	if err != nil {
+		return err
+	}
+	for _, s := range addresses {
+		addr, err := ParseAddress(s)
+		if err != nil {
+			return err
+		}
+		result.Addresses = append(result.Addresses, addr)
+	}
+
+	for _, position := range raw.Topics {
+		topics, err := decodeOneOrMany(position)
+		if err != nil {
+			return err
+		}
+		alternatives := make([]Hash, 0, len(topics))
+		for _, s := range topics {
+			hash, err := ParseHash(s)
+			if err != nil {
+				return err
+			}
+			alternatives = append(alternatives, hash)
+		}
+		result.Topics = append(result.Topics, alternatives)
+	}
+
+	*f = result
+	return nil
+}
+
+// DecodeBlockBound returns the block number a fromBlock or toBlock of a
+// filter object stands for. A missing block, "latest" and "pending" are the
+// head block, like eth_getLogs does, nil if head is nil. "safe" and
+// "finalized" are not known to the client and give nil, an unbounded range.
+func DecodeBlockBound(block string, head *big.Int) (*big.Int, error) {
+	switch block {
+	case "earliest":
+		return big.NewInt(0), nil
+	case "", "latest", "pending":
+		return head, nil
+	case "safe", "finalized":
+		return nil, nil
+	}
+	return DecodeQuantity(block)
+}
+
+// decodeOneOrMany decodes null, a string or an array of strings.
+func decodeOneOrMany(data json.RawMessage) ([]string, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	if data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, err
+		}
+		return []string{s}, nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package common
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type FilterTestSuite struct {
+	suite.Suite
+	log Log
+}
+
+func (suite *FilterTestSuite) decode(raw string) *LogFilter {
+	filter := &LogFilter{}
+	assert.NoError(suite.T(), json.Unmarshal([]byte(raw), filter), "Should be no error")
+	return filter
+}
+
+func (suite *FilterTestSuite) Test_Unmarshal() {
+	filter := suite.decode(`{"fromBlock":"earliest","toBlock":"0x1b4",` +
+		`"address":"0x16c5785ac562ff41e2dcfdf829c5a142f1fccd7d",` +
+		`"topics":[null,["0x59ebeb90bc63057b6515673c3ecf9438e5058bca0f92585014eced636878c9a5"]]}`)
+	assert.Equal(suite.T(), big.NewInt(0), filter.FromBlock, "Should be equal")
+	assert.Equal(suite.T(), big.NewInt(0x1b4), filter.ToBlock, "Should be equal")
+	assert.Equal(suite.T(), []Address{suite.log.Address}, filter.Addresses, "Should be equal")
+	assert.Equal(suite.T(), [][]Hash{{}, {NewHash(suite.log.Topics[0].Data)}}, filter.Topics, "Should be equal")
+
+	filter = &LogFilter{}
+	assert.Error(suite.T(), json.Unmarshal([]byte(`{"address":"0x16"}`), filter), "Should be an error")
+	assert.Error(suite.T(), json.Unmarshal([]byte(`{"toBlock":"head"}`), filter), "Should be an error")
+	assert.Error(suite.T(), json.Unmarshal([]byte(`{"topics":[42]}`), filter), "Should be an error")
+}
+
+func (suite *FilterTestSuite) Test_DecodeBlockBound() {
+	head := big.NewInt(0x4b7)
+	tests := []struct {
+		block string
+		bound *big.Int
+	}{
+		{"", head},
+		{"latest", head},
+		{"pending", head},
+		{"earliest", big.NewInt(0)},
+		{"safe", nil},
+		{"finalized", nil},
+		{"0x1b4", big.NewInt(0x1b4)},
+	}
+	for _, test := range tests {
+		bound, err := DecodeBlockBound(test.block, head)
+		assert.NoError(suite.T(), err, "Should be no error")
+		assert.Equal(suite.T(), test.bound, bound, test.block)
+	}
+
+	bound, err := DecodeBlockBound("latest", nil)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Nil(suite.T(), bound, "Should be unbounded")
+	_, err = DecodeBlockBound("head", head)
+	assert.Error(suite.T(), err, "Should be an error")
+}
+
+func (suite *FilterTestSuite) Test_Match() {
+	topic := `"0x59ebeb90bc63057b6515673c3ecf9438e5058bca0f92585014eced636878c9a5"`
+	other := `"0xc6ef2fc5426d6ad6fd9e2a26abeab0aa2411b7ab17f30a99d3cb96aed1d1055b"`
+	tests := []struct {
+		filter  string
+		matched bool
+	}{
+		{`{}`, true},
+		{`{"fromBlock":"0x1b4","toBlock":"latest"}`, true},
+		{`{"fromBlock":"0x1b5"}`, false},
+		{`{"toBlock":"0x1b3"}`, false},
+		{`{"address":["0x407d73d8a49eeb85d32cf465507dd71d507100c1","0x16c5785ac562ff41e2dcfdf829c5a142f1fccd7d"]}`, true},
+		{`{"address":"0x407d73d8a49eeb85d32cf465507dd71d507100c1"}`, false},
+		{`{"topics":[` + topic + `]}`, true},
+		{`{"topics":[[` + other + `,` + topic + `]]}`, true},
+		{`{"topics":[` + other + `]}`, false},
+		{`{"topics":[null]}`, true},
+		{`{"topics":[null,null]}`, false},
+		{`{"blockHash":` + other + `}`, false},
+	}
+	for _, test := range tests {
+		assert.Equal(suite.T(), test.matched, suite.decode(test.filter).Match(&suite.log), test.filter)
+	}
+}
+
+func (suite *FilterTestSuite) SetupTest() {
+	suite.log = Log{
+		LogIndex:    0x1,
+		BlockNumber: big.NewInt(0x1b4),
+		Address:     NewAddress(HexToBytes("0x16c5785ac562ff41e2dcfdf829c5a142f1fccd7d")),
+		Topics: Topics{
+			{Data: HexToBytes("0x59ebeb90bc63057b6515673c3ecf9438e5058bca0f92585014eced636878c9a5")},
+		},
+	}
+}
+
+func Test_FilterTestSuite(t *testing.T) {
+	suite.Run(t, new(FilterTestSuite))
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+var (
+	ErrEmptyHex      = errors.New("Empty hex string")
+	ErrMissingPrefix = errors.New("Hex string without 0x prefix")
+	ErrOddLength     = errors.New("Hex string of odd length")
+	ErrInvalidHex    = errors.New("Invalid hex string")
+	ErrEmptyQuantity = errors.New("Hex string \"0x\"")
+	ErrLeadingZero   = errors.New("Hex quantity with leading zero digits")
+	ErrUint64Range   = errors.New("Hex quantity larger than 64 bits")
+)
+
+const (
+	hexDigits  = "0123456789abcdef"
+	badNibble  = 0xff
+	hexPrefix  = "0x"
+	uint64Bits = 64
+)
+
+var nibbleTable [256]byte
+
+func init() {
+	for i := range nibbleTable {
+		nibbleTable[i] = badNibble
+	}
+	for i := byte('0'); i <= '9'; i++ {
+		nibbleTable[i] = i - '0'
+	}
+	for i := byte('a'); i <= 'f'; i++ {
+		nibbleTable[i] = i - 'a' + 10
+		nibbleTable[i-'a'+'A'] = i - 'a' + 10
+	}
+}
+
+// EncodeHex encodes b as a 0x prefixed hex string. Empty input encodes as "0x".
+func EncodeHex(b []byte) string {
+	result := make([]byte, len(hexPrefix)+len(b)*2)
+	copy(result, hexPrefix)
+	encodeHexDigits(result[len(hexPrefix):], b)
+	return string(result)
+}
+
+// DecodeHex decodes a 0x prefixed hex string with an even number of digits.
+func DecodeHex(s string) ([]byte, error) {
+	raw, err := stripHexPrefix(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw)%2 != 0 {
+		return nil, ErrOddLength
+	}
+	result := make([]byte, len(raw)/2)
+	if err := decodeHexDigits(result, raw); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DecodeHexInto decodes a 0x prefixed hex string into dst, which must be
+// exactly as long as the decoded data. It allows callers to reuse buffers when
+// decoding large payloads.
+func DecodeHexInto(dst []byte, s string) error {
+	raw, err := stripHexPrefix(s)
+	if err != nil {
+		return err
+	}
+	if len(raw)%2 != 0 {
+		return ErrOddLength
+	}
+	if len(raw)/2 != len(dst) {
+		return fmt.Errorf("Hex string has %d digits, want %d", len(raw), len(dst)*2)
+	}
+	return decodeHexDigits(dst, raw)
+}
+
+// EncodeUint64Quantity encodes i as a hex quantity, e.g. "0x0" or "0x1b4".
+func EncodeUint64Quantity(i uint64) string {
+	return hexPrefix + strconv.FormatUint(i, 16)
+}
+
+// DecodeUint64Quantity decodes a hex quantity into a uint64.
+func DecodeUint64Quantity(s string) (uint64, error) {
+	raw, err := checkQuantity(s)
+	if err != nil {
+		return 0, err
+	}
+	if len(raw) > uint64Bits/4 {
+		return 0, ErrUint64Range
+	}
+	var result uint64
+	for i := 0; i < len(raw); i++ {
+		nibble := nibbleTable[raw[i]]
+		if nibble == badNibble {
+			return 0, ErrInvalidHex
+		}
+		result = result<<4 | uint64(nibble)
+	}
+	return result, nil
+}
+
+// EncodeQuantity encodes a non-negative big integer as a hex quantity. Nil
+// encodes as "0x0".
+func EncodeQuantity(i *big.Int) string {
+	if i == nil || i.Sign() == 0 {
+		return "0x0"
+	}
+	return hexPrefix + i.Text(16)
+}
+
+// DecodeQuantity decodes a hex quantity into a big integer. Quantities must be
+// 0x prefixed and must not have leading zero digits, except for "0x0".
+func DecodeQuantity(s string) (*big.Int, error) {
+	raw, err := checkQuantity(s)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(raw); i++ {
+		if nibbleTable[raw[i]] == badNibble {
+			return nil, ErrInvalidHex
+		}
+	}
+	result, _ := new(big.Int).SetString(raw, 16)
+	return result, nil
+}
+
+func stripHexPrefix(s string) (string, error) {
+	if len(s) == 0 {
+		return "", ErrEmptyHex
+	}
+	if !hasHexPrefix(s) {
+		return "", ErrMissingPrefix
+	}
+	return s[len(hexPrefix):], nil
+}
+
+func checkQuantity(s string) (string, error) {
+	raw, err := stripHexPrefix(s)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) == 0 {
+		return "", ErrEmptyQuantity
+	}
+	if len(raw) > 1 && raw[0] == '0' {
+		return "", ErrLeadingZero
+	}
+	return raw, nil
+}
+
+func hasHexPrefix(s string) bool {
+	return len(s) >= 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X')
+}
+
+func encodeHexDigits(dst, src []byte) {
+	for i, b := range src {
+		dst[i*2] = hexDigits[b>>4]
+		dst[i*2+1] = hexDigits[b&0x0f]
+	}
+}
+
+func decodeHexDigits(dst []byte, raw string) error {
+	for i := 0; i < len(dst); i++ {
+		hi := nibbleTable[raw[i*2]]
+		lo := nibbleTable[raw[i*2+1]]
+		if hi == badNibble || lo == badNibble {
+			return ErrInvalidHex
+		}
+		dst[i] = hi<<4 | lo
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package common
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type HexTestSuite struct {
+	suite.Suite
+}
+
+func (suite *HexTestSuite) Test_EncodeHex() {
+	assert.EqualValues(suite.T(), "0x", EncodeHex(nil), "Should be equal")
+	assert.EqualValues(suite.T(), "0x00ff10", EncodeHex([]byte{0x00, 0xff, 0x10}), "Should be equal")
+	assert.EqualValues(suite.T(), "0x00ff10", BytesToHex([]byte{0x00, 0xff, 0x10}), "Should be equal")
+}
+
+func (suite *HexTestSuite) Test_DecodeHex() {
+	b, err := DecodeHex("0x00fF10")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), []byte{0x00, 0xff, 0x10}, b, "Should be equal")
+
+	b, err = DecodeHex("0x")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Empty(suite.T(), b, "Should be empty")
+
+	_, err = DecodeHex("")
+	assert.Equal(suite.T(), ErrEmptyHex, err, "Should be equal")
+	_, err = DecodeHex("00ff")
+	assert.Equal(suite.T(), ErrMissingPrefix, err, "Should be equal")
+	_, err = DecodeHex("0x0ff")
+	assert.Equal(suite.T(), ErrOddLength, err, "Should be equal")
+	_, err = DecodeHex("0x0g")
+	assert.Equal(suite.T(), ErrInvalidHex, err, "Should be equal")
+
+	dst := make([]byte, 2)
+	assert.NoError(suite.T(), DecodeHexInto(dst, "0xabcd"), "Should be no error")
+	assert.EqualValues(suite.T(), []byte{0xab, 0xcd}, dst, "Should be equal")
+	assert.Error(suite.T(), DecodeHexInto(dst, "0xab"), "Should be error")
+}
+
+func (suite *HexTestSuite) Test_Quantity() {
+	assert.EqualValues(suite.T(), "0x0", EncodeQuantity(nil), "Should be equal")
+	assert.EqualValues(suite.T(), "0x0", EncodeQuantity(big.NewInt(0)), "Should be equal")
+	assert.EqualValues(suite.T(), "0x1b4", EncodeQuantity(big.NewInt(0x1b4)), "Should be equal")
+	assert.EqualValues(suite.T(), "0x0", EncodeUint64Quantity(0), "Should be equal")
+	assert.EqualValues(suite.T(), "0x400", EncodeUint64Quantity(1024), "Should be equal")
+
+	i, err := DecodeQuantity("0x1B4")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), big.NewInt(0x1b4), i, "Should be equal")
+	i, err = DecodeQuantity("0x0")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), 0, i.Int64(), "Should be equal")
+
+	_, err = DecodeQuantity("0x")
+	assert.Equal(suite.T(), ErrEmptyQuantity, err, "Should be equal")
+	_, err = DecodeQuantity("0x01")
+	assert.Equal(suite.T(), ErrLeadingZero, err, "Should be equal")
+	_, err = DecodeQuantity("1")
+	assert.Equal(suite.T(), ErrMissingPrefix, err, "Should be equal")
+	_, err = DecodeQuantity("0x-1")
+	assert.Equal(suite.T(), ErrInvalidHex, err, "Should be equal")
+
+	u, err := DecodeUint64Quantity("0xffffffffffffffff")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), uint64(0xffffffffffffffff), u, "Should be equal")
+	_, err = DecodeUint64Quantity("0x10000000000000000")
+	assert.Equal(suite.T(), ErrUint64Range, err, "Should be equal")
+}
+
+func (suite *HexTestSuite) Test_IsHex() {
+	assert.True(suite.T(), IsHex("0x1aF"), "Should be true")
+	assert.False(suite.T(), IsHex("0x"), "Should be false")
+	assert.False(suite.T(), IsHex("1a"), "Should be false")
+	assert.False(suite.T(), IsHex("zz0x1a"), "Should be false")
+	assert.False(suite.T(), IsHex("0x1az"), "Should be false")
+}
+
+func (suite *HexTestSuite) Test_HexToBytes() {
+	assert.EqualValues(suite.T(), []byte{0xab, 0xcd}, HexToBytes("0xabcd"), "Should be equal")
+	assert.EqualValues(suite.T(), []byte{0xab, 0xcd}, HexToBytes("abcde"), "Should be equal")
+	assert.EqualValues(suite.T(), []byte{0x00, 0xcd}, HexToBytes("0xzzcd"), "Should be equal")
+}
+
+func Test_HexTestSuite(t *testing.T) {
+	suite.Run(t, new(HexTestSuite))
+}
+
+func benchmarkPayload() string {
+	return "0x" + strings.Repeat("600160008035811a818181146012578301005b601b", 1<<12)
+}
+
+func BenchmarkDecodeHex(b *testing.B) {
+	payload := benchmarkPayload()
+	b.SetBytes(int64(len(payload)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecodeHex(payload); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeHexInto(b *testing.B) {
+	payload := benchmarkPayload()
+	dst := make([]byte, (len(payload)-2)/2)
+	b.SetBytes(int64(len(payload)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := DecodeHexInto(dst, payload); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodeHex(b *testing.B) {
+	data := HexToBytes(benchmarkPayload())
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		EncodeHex(data)
+	}
+}
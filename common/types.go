@@ -63,6 +63,53 @@ func (addr *Address) String() string {
 	return BytesToHex(addr[:])
 }
 
+// Hex returns the EIP-55 mixed-case checksum encoding of the address.
+func (addr Address) Hex() string {
+	return addr.checksumHex(nil)
+}
+
+// EIP1191Chains lists the chain ids that adopted the EIP-1191 checksum, RSK
+// mainnet and testnet.
+var EIP1191Chains = map[int64]bool{
+	30: true,
+	31: true,
+}
+
+// ChainHex returns the EIP-1191 checksum encoding of the address for the given
+// chain id. Chains not listed in EIP1191Chains, and a nil chain id, fall back
+// to plain EIP-55.
+func (addr Address) ChainHex(chainID *big.Int) string {
+	if chainID == nil || !chainID.IsInt64() || !EIP1191Chains[chainID.Int64()] {
+		return addr.checksumHex(nil)
+	}
+	return addr.checksumHex(chainID)
+}
+
+func (addr Address) checksumHex(chainID *big.Int) string {
+	lower := BytesToHex(addr[:])
+	hashed := []byte(lower[2:])
+	if chainID != nil {
+		hashed = []byte(chainID.String() + lower)
+	}
+	digest := Keccak256(hashed)
+
+	result := []byte(lower)
+	for i := 2; i < len(result); i++ {
+		c := result[i]
+		if c < 'a' || c > 'f' {
+			continue
+		}
+		nibble := digest[(i-2)/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		}
+		if nibble&0x0f > 7 {
+			result[i] = c - 'a' + 'A'
+		}
+	}
+	return string(result)
+}
+
 // SyncStatus ...
 type SyncStatus struct {
 	Result        bool
@@ -122,6 +169,7 @@ type Log struct {
 	Address          Address  `json:"address"`
 	Data             []byte   `json:"data"`
 	Topics           Topics   `json:"topics"`
+	Removed          bool     `json:"removed"`
 }
 
 // TransactionReceipt ...
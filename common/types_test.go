@@ -0,0 +1,110 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package common
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TypesTestSuite struct {
+	suite.Suite
+}
+
+func (suite *TypesTestSuite) Test_AddressHex() {
+	addresses := []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	}
+	for _, s := range addresses {
+		addr := StringToAddress(strings.ToLower(s))
+		assert.EqualValues(suite.T(), s, addr.Hex(), "Should be equal")
+		assert.EqualValues(suite.T(), strings.ToLower(s), addr.String(), "Should be equal")
+	}
+}
+
+func (suite *TypesTestSuite) Test_AddressChainHex() {
+	addr := StringToAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+	assert.EqualValues(suite.T(), "0x5aaEB6053f3e94c9b9a09f33669435E7ef1bEAeD", addr.ChainHex(big.NewInt(30)), "Should be equal")
+	assert.EqualValues(suite.T(), "0x5aAeb6053F3e94c9b9A09F33669435E7EF1BEaEd", addr.ChainHex(big.NewInt(31)), "Should be equal")
+	assert.EqualValues(suite.T(), addr.Hex(), addr.ChainHex(nil), "Should be equal")
+	assert.EqualValues(suite.T(), addr.Hex(), addr.ChainHex(big.NewInt(1)), "Should be equal")
+}
+
+func (suite *TypesTestSuite) Test_ParseAddress() {
+	addr, err := ParseAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), StringToAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"), addr, "Should be equal")
+
+	_, err = ParseAddress("5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+	assert.NoError(suite.T(), err, "Should be no error")
+	_, err = ParseAddress("0x5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED")
+	assert.NoError(suite.T(), err, "Should be no error")
+
+	_, err = ParseAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD")
+	assert.Equal(suite.T(), ErrInvalidChecksum, err, "Should be equal")
+	_, err = ParseAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1bea")
+	assert.Equal(suite.T(), ErrInvalidAddressLength, err, "Should be equal")
+	_, err = ParseAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beazz")
+	assert.Error(suite.T(), err, "Should be error")
+
+	_, err = ParseChainAddress("0x5aaEB6053f3e94c9b9a09f33669435E7ef1bEAeD", big.NewInt(30))
+	assert.NoError(suite.T(), err, "Should be no error")
+	_, err = ParseChainAddress("0x5aaEB6053f3e94c9b9a09f33669435E7ef1bEAeD", big.NewInt(31))
+	assert.Equal(suite.T(), ErrInvalidChecksum, err, "Should be equal")
+
+	// Mainnet did not adopt EIP-1191.
+	_, err = ParseChainAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", big.NewInt(1))
+	assert.NoError(suite.T(), err, "Should be no error")
+	_, err = ParseChainAddress("0x5aaEB6053f3e94c9b9a09f33669435E7ef1bEAeD", big.NewInt(1))
+	assert.Equal(suite.T(), ErrInvalidChecksum, err, "Should be equal")
+}
+
+func (suite *TypesTestSuite) Test_ParseHash() {
+	s := "0xc6ef2fc5426d6ad6fd9e2a26abeab0aa2411b7ab17f30a99d3cb96aed1d1055b"
+	hash, err := ParseHash(s)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), s, hash.String(), "Should be equal")
+
+	_, err = ParseHash("0xe670ec64341771606e55d6b4ca35a1a6b75ee3d5145a99d05921026d1527331")
+	assert.Equal(suite.T(), ErrInvalidHashLength, err, "Should be equal")
+	_, err = ParseHash("0xc6ef2fc5426d6ad6fd9e2a26abeab0aa2411b7ab17f30a99d3cb96aed1d1055g")
+	assert.Error(suite.T(), err, "Should be error")
+}
+
+func Test_TypesTestSuite(t *testing.T) {
+	suite.Run(t, new(TypesTestSuite))
+}
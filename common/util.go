@@ -32,14 +32,31 @@ package common
 import (
 	"bytes"
 	"encoding/gob"
-	"regexp"
-	"strconv"
+	"errors"
+	"math/big"
 	"strings"
+
+	"github.com/tonnerre/golang-go.crypto/sha3"
+)
+
+var (
+	ErrInvalidAddressLength = errors.New("Invalid address length")
+	ErrInvalidHashLength    = errors.New("Invalid hash length")
+	ErrInvalidChecksum      = errors.New("Invalid address checksum")
 )
 
+// IsHex checks if the whole string is a 0x prefixed hex string with at least
+// one digit.
 func IsHex(hex string) bool {
-	hexMatcher := regexp.MustCompile("0[xX][0-9a-fA-F]+")
-	return hexMatcher.MatchString(hex)
+	if !hasHexPrefix(hex) || len(hex) == len(hexPrefix) {
+		return false
+	}
+	for i := len(hexPrefix); i < len(hex); i++ {
+		if nibbleTable[hex[i]] == badNibble {
+			return false
+		}
+	}
+	return true
 }
 
 func HexToString(hex string) string {
@@ -50,27 +67,21 @@ func HexToString(hex string) string {
 }
 
 func BytesToHex(data []byte) string {
-	buffer := new(bytes.Buffer)
-	for _, b := range data {
-		s := strconv.FormatInt(int64(b&0xFF), 16)
-		if len(s) == 1 {
-			buffer.WriteString("0")
-		}
-		buffer.WriteString(s)
-	}
-	return "0x" + buffer.String()
+	return EncodeHex(data)
 }
 
+// HexToBytes converts a hex string, with or without 0x prefix, into bytes. It
+// is lenient: a trailing odd digit is dropped and invalid digit pairs decode
+// as zero. Use DecodeHex to get errors for malformed input instead.
 func HexToBytes(hex string) []byte {
 	hex = HexToString(hex)
-	length := len(hex) / 2
-	slice := make([]byte, length)
-	rs := []rune(hex)
-
-	for i := 0; i < length; i++ {
-		s := string(rs[i*2 : i*2+2])
-		value, _ := strconv.ParseInt(s, 16, 10)
-		slice[i] = byte(value & 0xFF)
+	slice := make([]byte, len(hex)/2)
+	for i := range slice {
+		hi := nibbleTable[hex[i*2]]
+		lo := nibbleTable[hex[i*2+1]]
+		if hi != badNibble && lo != badNibble {
+			slice[i] = hi<<4 | lo
+		}
 	}
 	return slice
 }
@@ -87,6 +98,57 @@ func StringToHash(s string) (hash Hash) {
 	return hash
 }
 
+// ParseAddress parses a hex encoded address. Unlike StringToAddress, it fails
+// on wrong length or non-hex characters, and validates the EIP-55 checksum
+// when the input is mixed-case.
+func ParseAddress(s string) (Address, error) {
+	return ParseChainAddress(s, nil)
+}
+
+// ParseChainAddress is like ParseAddress, but validates mixed-case input
+// against the EIP-1191 checksum for the given chain id. Chains which did not
+// adopt EIP-1191 use EIP-55, see EIP1191Chains.
+func ParseChainAddress(s string, chainID *big.Int) (addr Address, err error) {
+	raw := HexToString(s)
+	if len(raw) != addressLength*2 {
+		return addr, ErrInvalidAddressLength
+	}
+	if err := decodeHexDigits(addr[:], raw); err != nil {
+		return Address{}, err
+	}
+
+	if raw == strings.ToLower(raw) || raw == strings.ToUpper(raw) {
+		return addr, nil
+	}
+	if addr.ChainHex(chainID)[2:] != raw {
+		return Address{}, ErrInvalidChecksum
+	}
+	return addr, nil
+}
+
+// ParseHash parses a hex encoded 32-byte hash, failing on wrong length or
+// non-hex characters.
+func ParseHash(s string) (hash Hash, err error) {
+	raw := HexToString(s)
+	if len(raw) != hashLength*2 {
+		return hash, ErrInvalidHashLength
+	}
+	if err := decodeHexDigits(hash[:], raw); err != nil {
+		return Hash{}, err
+	}
+	return hash, nil
+}
+
+// Keccak256 returns Keccak-256 (not the standardized SHA3-256) of the given
+// data.
+func Keccak256(data ...[]byte) []byte {
+	d := sha3.NewKeccak256()
+	for _, b := range data {
+		d.Write(b)
+	}
+	return d.Sum(nil)
+}
+
 func ToBytes(data interface{}) ([]byte, error) {
 	var buf bytes.Buffer
 	enc := gob.NewEncoder(&buf)
@@ -69,11 +69,15 @@ func main() {
 	if filterCh := filter.Watch(); filterCh != nil {
 		for {
 			log, err := filterCh.Next()
-			if err == nil {
+			switch err {
+			case nil:
 				fmt.Printf("Block: %v\n", log)
-			} else {
+			case web3.ErrChannelClosed, web3.ErrWatchOverflow:
 				fmt.Printf("%v\n", err)
 				return
+			default:
+				// Polling goes on after transient errors.
+				fmt.Printf("%v\n", err)
 			}
 		}
 	}
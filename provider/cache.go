@@ -0,0 +1,288 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"container/list"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/alanchchen/web3go/rpc"
+)
+
+const cacheSize = 1024
+
+// immutableMethods are cached whatever their parameters.
+var immutableMethods = map[string]bool{
+	"eth_chainId":                           true,
+	"net_version":                           true,
+	"eth_getBlockByHash":                    true,
+	"eth_getBlockTransactionCountByHash":    true,
+	"eth_getUncleCountByBlockHash":          true,
+	"eth_getUncleByBlockHashAndIndex":       true,
+	"eth_getTransactionByHash":              true,
+	"eth_getTransactionByBlockHashAndIndex": true,
+	"eth_getTransactionReceipt":             true,
+}
+
+// stateMethods are cached when queried at a fixed block, they map to the
+// position of the block parameter.
+var stateMethods = map[string]int{
+	"eth_getBalance":          1,
+	"eth_getCode":             1,
+	"eth_getTransactionCount": 1,
+	"eth_getStorageAt":        2,
+	"eth_call":                1,
+}
+
+// IsCacheable tells if the response to a request never changes: blocks and
+// transactions by hash, receipts, the chain id, and state queried at a block
+// number or hash. Queries at "latest" or "pending" are never cacheable.
+func IsCacheable(method string, params []interface{}) bool {
+	if immutableMethods[method] {
+		return true
+	}
+	index, ok := stateMethods[method]
+	if !ok || len(params) <= index {
+		return false
+	}
+	switch block := params[index].(type) {
+	case string:
+		switch block {
+		case "latest", "pending", "safe", "finalized":
+			return false
+		}
+		return true
+	case map[string]interface{}:
+		// EIP-1898 block object, by hash or number.
+		return true
+	}
+	return false
+}
+
+// CacheOption configures a Cache. Zero fields take defaults.
+type CacheOption struct {
+	// Size is the maximum number of responses kept, 1024 by default.
+	Size int
+	// TTL is how long a response is kept, forever if zero.
+	TTL time.Duration
+	// Cacheable tells if a request can be cached, IsCacheable by default.
+	Cacheable func(method string, params []interface{}) bool
+}
+
+// CacheStats counts the cacheable requests.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+}
+
+type cacheEntry struct {
+	key     string
+	result  json.RawMessage
+	expires time.Time
+}
+
+// Cache keeps the responses of requests for immutable data in an LRU. Error
+// responses, null results, e.g. an unknown receipt, and pending transactions
+// are not cached.
+type Cache struct {
+	option CacheOption
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	stats   CacheStats
+}
+
+// NewCache creates an empty cache.
+func NewCache(option *CacheOption) *Cache {
+	c := &Cache{entries: make(map[string]*list.Element), order: list.New()}
+	if option != nil {
+		c.option = *option
+	}
+	if c.option.Size <= 0 {
+		c.option.Size = cacheSize
+	}
+	if c.option.Cacheable == nil {
+		c.option.Cacheable = IsCacheable
+	}
+	return c
+}
+
+// Stats returns the statistics of the cache.
+func (c *Cache) Stats() CacheStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	stats := c.stats
+	stats.Entries = c.order.Len()
+	return stats
+}
+
+// Purge empties the cache.
+func (c *Cache) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+// Wrap returns next with responses cached, it is a Middleware.
+func (c *Cache) Wrap(next Provider) Provider {
+	method := next.GetRPCMethod()
+	send := func(request rpc.Request, next SendFunc) (rpc.Response, error) {
+		key, ok := c.key(request)
+		if !ok {
+			return next(request)
+		}
+		if response := c.get(key, method, request.ID()); response != nil {
+			return response, nil
+		}
+		response, err := next(request)
+		if err == nil {
+			c.put(key, response)
+		}
+		return response, err
+	}
+	batch := func(requests []rpc.Request, next BatchSendFunc) ([]rpc.Response, error) {
+		responses := make([]rpc.Response, len(requests))
+		keys := make([]string, len(requests))
+		var missing []rpc.Request
+		var indexes []int
+		for i, request := range requests {
+			var ok bool
+			if keys[i], ok = c.key(request); ok {
+				responses[i] = c.get(keys[i], method, request.ID())
+			}
+			if responses[i] == nil {
+				missing = append(missing, request)
+				indexes = append(indexes, i)
+			}
+		}
+		if len(missing) == 0 {
+			return responses, nil
+		}
+
+		sent, err := next(missing)
+		if err != nil {
+			return nil, err
+		}
+		for j, response := range sent {
+			i := indexes[j]
+			responses[i] = response
+			if keys[i] != "" {
+				c.put(keys[i], response)
+			}
+		}
+		return responses, nil
+	}
+	return Intercept(send, batch)(next)
+}
+
+// key returns the cache key of request, if it is cacheable. Parameters are
+// compared in their JSON form.
+func (c *Cache) key(request rpc.Request) (string, bool) {
+	method, _ := request.Get("method").(string)
+	data, err := json.Marshal(requestParams(request))
+	if err != nil {
+		return "", false
+	}
+	var params []interface{}
+	if err := json.Unmarshal(data, &params); err != nil {
+		return "", false
+	}
+	if !c.option.Cacheable(method, params) {
+		return "", false
+	}
+	return method + string(data), true
+}
+
+// get returns the cached response to request id, or nil.
+func (c *Cache) get(key string, method rpc.RPC, id uint64) rpc.Response {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	element, ok := c.entries[key]
+	if ok && c.option.TTL > 0 && time.Now().After(element.Value.(*cacheEntry).expires) {
+		c.remove(element)
+		ok = false
+	}
+	if !ok {
+		c.stats.Misses++
+		return nil
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(element)
+	// The response is decoded again so callers don't share results.
+	return newResponse(method, id, element.Value.(*cacheEntry).result, nil)
+}
+
+func (c *Cache) put(key string, response rpc.Response) {
+	if response == nil || response.Error() != nil || response.Get("result") == nil {
+		return
+	}
+	if isPending(response.Get("result")) {
+		return
+	}
+	result, err := json.Marshal(response.Get("result"))
+	if err != nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	entry := &cacheEntry{key: key, result: result, expires: time.Now().Add(c.option.TTL)}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.option.Size {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+// isPending tells if result is a pending transaction, which has a null
+// blockHash until it is mined.
+func isPending(result interface{}) bool {
+	fields, ok := result.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	blockHash, ok := fields["blockHash"]
+	return ok && blockHash == nil
+}
+
+func (c *Cache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).key)
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alanchchen/web3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	testTxHash      = "0xc6ef2fc5426d6ad6fd9e2a26abeab0aa2411b7ab17f30a99d3cb96aed1d1055b"
+	testAddress     = "0x407d73d8a49eeb85d32cf465507dd71d507100c1"
+	testMissingHash = "0xe670ec64341771606e55d6b4ca35a1a6b75ee3d5145a99d05921026d15273311"
+	testPendingHash = "0x8a6c9f9f14f1d3f4c5a3b6e5d5b2f7e0f1a9c8d7e6b5a4f3e2d1c0b9a8f7e6d5"
+)
+
+type CacheTestSuite struct {
+	suite.Suite
+	stub  *stubProvider
+	cache *Cache
+	p     Provider
+}
+
+func (suite *CacheTestSuite) send(method string, params ...interface{}) rpc.Response {
+	request := suite.p.GetRPCMethod().NewRequest(method)
+	request.Set("params", params)
+	response, err := suite.p.Send(request)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), request.ID(), response.ID(), "Should be equal")
+	return response
+}
+
+func (suite *CacheTestSuite) Test_IsCacheable() {
+	assert.True(suite.T(), IsCacheable("eth_chainId", nil), "Should be cacheable")
+	assert.True(suite.T(), IsCacheable("eth_getBlockByHash", []interface{}{testTxHash, false}), "Should be cacheable")
+	assert.True(suite.T(), IsCacheable("eth_getCode", []interface{}{testAddress, "0x10"}), "Should be cacheable")
+	assert.True(suite.T(), IsCacheable("eth_getStorageAt", []interface{}{testAddress, "0x0", "earliest"}), "Should be cacheable")
+	assert.True(suite.T(), IsCacheable("eth_call", []interface{}{map[string]interface{}{}, map[string]interface{}{"blockHash": testTxHash}}), "Should be cacheable")
+
+	assert.False(suite.T(), IsCacheable("eth_getCode", []interface{}{testAddress, "latest"}), "Should not be cacheable")
+	assert.False(suite.T(), IsCacheable("eth_getBalance", []interface{}{testAddress, "pending"}), "Should not be cacheable")
+	assert.False(suite.T(), IsCacheable("eth_getBalance", []interface{}{testAddress}), "Should not be cacheable")
+	assert.False(suite.T(), IsCacheable("eth_getBlockByNumber", []interface{}{"0x10", false}), "Should not be cacheable")
+	assert.False(suite.T(), IsCacheable("eth_blockNumber", nil), "Should not be cacheable")
+}
+
+func (suite *CacheTestSuite) Test_Hit() {
+	first := suite.send("eth_getTransactionByHash", testTxHash)
+	second := suite.send("eth_getTransactionByHash", testTxHash)
+	assert.Equal(suite.T(), first.Get("result"), second.Get("result"), "Should be equal")
+	assert.Equal(suite.T(), 1, suite.stub.count("eth_getTransactionByHash"), "Should be equal")
+
+	suite.send("eth_getCode", testAddress, "0x10")
+	suite.send("eth_getCode", testAddress, "0x10")
+	suite.send("eth_getCode", testAddress, "0x11")
+	assert.Equal(suite.T(), 2, suite.stub.count("eth_getCode"), "Should be equal")
+
+	assert.Equal(suite.T(), CacheStats{Hits: 2, Misses: 3, Entries: 3}, suite.cache.Stats(), "Should be equal")
+}
+
+func (suite *CacheTestSuite) Test_Uncacheable() {
+	suite.send("eth_getCode", testAddress, "latest")
+	suite.send("eth_getCode", testAddress, "latest")
+	suite.send("eth_blockNumber")
+	suite.send("eth_blockNumber")
+	assert.Equal(suite.T(), 2, suite.stub.count("eth_getCode"), "Should be equal")
+	assert.Equal(suite.T(), 2, suite.stub.count("eth_blockNumber"), "Should be equal")
+	assert.Equal(suite.T(), CacheStats{}, suite.cache.Stats(), "Should be equal")
+}
+
+func (suite *CacheTestSuite) Test_NullAndErrors() {
+	suite.send("eth_getTransactionByHash", testMissingHash)
+	suite.send("eth_getTransactionByHash", testMissingHash)
+	assert.Equal(suite.T(), 2, suite.stub.count("eth_getTransactionByHash"), "Should be equal")
+
+	suite.send("eth_getTransactionReceipt", testTxHash)
+	suite.send("eth_getTransactionReceipt", testTxHash)
+	assert.Equal(suite.T(), 2, suite.stub.count("eth_getTransactionReceipt"), "Should be equal")
+}
+
+func (suite *CacheTestSuite) Test_Pending() {
+	pending := suite.send("eth_getTransactionByHash", testPendingHash)
+	assert.Nil(suite.T(), pending.Get("result").(map[string]interface{})["blockHash"], "Should be pending")
+	suite.send("eth_getTransactionByHash", testPendingHash)
+	assert.Equal(suite.T(), 2, suite.stub.count("eth_getTransactionByHash"), "Should be equal")
+	assert.Equal(suite.T(), 0, suite.cache.Stats().Entries, "Should be equal")
+}
+
+func (suite *CacheTestSuite) Test_Copies() {
+	first := suite.send("eth_getBlockByHash", testTxHash, false)
+	first.Get("result").(map[string]interface{})["hash"] = "0x0"
+	second := suite.send("eth_getBlockByHash", testTxHash, false)
+	assert.Equal(suite.T(), testTxHash, second.Get("result").(map[string]interface{})["hash"], "Should be equal")
+}
+
+func (suite *CacheTestSuite) Test_Eviction() {
+	cache := NewCache(&CacheOption{Size: 2})
+	suite.p = cache.Wrap(suite.stub)
+	suite.send("eth_getCode", testAddress, "0x1")
+	suite.send("eth_getCode", testAddress, "0x2")
+	suite.send("eth_getCode", testAddress, "0x1")
+	suite.send("eth_getCode", testAddress, "0x3")
+
+	suite.send("eth_getCode", testAddress, "0x1")
+	assert.Equal(suite.T(), 3, suite.stub.count("eth_getCode"), "Should be equal")
+	suite.send("eth_getCode", testAddress, "0x2")
+	assert.Equal(suite.T(), 4, suite.stub.count("eth_getCode"), "Should be equal")
+	assert.Equal(suite.T(), uint64(2), cache.Stats().Evictions, "Should be equal")
+
+	cache.Purge()
+	assert.Equal(suite.T(), 0, cache.Stats().Entries, "Should be equal")
+}
+
+func (suite *CacheTestSuite) Test_TTL() {
+	suite.p = NewCache(&CacheOption{TTL: 10 * time.Millisecond}).Wrap(suite.stub)
+	suite.send("eth_chainId")
+	suite.send("eth_chainId")
+	assert.Equal(suite.T(), 1, suite.stub.count("eth_chainId"), "Should be equal")
+
+	time.Sleep(20 * time.Millisecond)
+	suite.send("eth_chainId")
+	assert.Equal(suite.T(), 2, suite.stub.count("eth_chainId"), "Should be equal")
+}
+
+func (suite *CacheTestSuite) Test_Batch() {
+	stub := &stubBatchProvider{stubProvider: suite.stub}
+	p := suite.cache.Wrap(stub).(BatchProvider)
+	method := p.GetRPCMethod()
+	suite.p = p
+	suite.send("eth_chainId")
+
+	chainID := method.NewRequest("eth_chainId")
+	blockNumber := method.NewRequest("eth_blockNumber")
+	responses, err := p.SendBatch([]rpc.Request{chainID, blockNumber})
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), chainID.ID(), responses[0].ID(), "Should be equal")
+	assert.Equal(suite.T(), "0x1", responses[0].Get("result"), "Should be equal")
+	assert.Equal(suite.T(), blockNumber.ID(), responses[1].ID(), "Should be equal")
+	assert.Equal(suite.T(), 1, suite.stub.count("eth_chainId"), "Should be equal")
+	assert.Equal(suite.T(), 1, stub.batches, "Should be equal")
+
+	_, err = p.SendBatch([]rpc.Request{method.NewRequest("eth_chainId")})
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), 1, stub.batches, "Should be equal")
+}
+
+func (suite *CacheTestSuite) SetupTest() {
+	suite.stub = newStubProvider(func(request rpc.Request) (interface{}, error) {
+		params := requestParams(request)
+		switch request.Get("method").(string) {
+		case "eth_chainId":
+			return "0x1", nil
+		case "eth_getTransactionByHash":
+			switch params[0] {
+			case testMissingHash:
+				return nil, nil
+			case testPendingHash:
+				return map[string]interface{}{"hash": params[0], "blockHash": nil}, nil
+			}
+			return map[string]interface{}{"hash": params[0], "blockHash": testTxHash}, nil
+		case "eth_getBlockByHash":
+			return map[string]interface{}{"hash": params[0]}, nil
+		case "eth_getTransactionReceipt":
+			return nil, &rpc.JSONRPCError{Code: rpc.CodeInternalError, Message: "Busy"}
+		}
+		return "0x60", nil
+	})
+	suite.cache = NewCache(nil)
+	suite.p = suite.cache.Wrap(suite.stub)
+}
+
+func Test_CacheTestSuite(t *testing.T) {
+	suite.Run(t, new(CacheTestSuite))
+}
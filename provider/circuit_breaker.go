@@ -0,0 +1,276 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/alanchchen/web3go/rpc"
+)
+
+var (
+	// ErrCircuitOpen is returned without sending the request while the
+	// circuit breaker is open.
+	ErrCircuitOpen = errors.New("Circuit breaker is open")
+)
+
+const (
+	breakerFailureThreshold = 5
+	breakerCoolDown         = 30 * time.Second
+)
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets requests through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails requests immediately.
+	CircuitOpen
+	// CircuitHalfOpen lets one request through at a time to test the
+	// provider.
+	CircuitHalfOpen
+)
+
+func (state CircuitState) String() string {
+	switch state {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreakerOption configures a CircuitBreaker. Zero fields take
+// defaults.
+type CircuitBreakerOption struct {
+	// FailureThreshold is the number of consecutive failures opening the
+	// circuit, 5 by default.
+	FailureThreshold int
+	// CoolDown is the delay before an open circuit probes the provider, 30s
+	// by default.
+	CoolDown time.Duration
+	// SuccessThreshold is the number of successful requests closing a half
+	// open circuit, 1 by default.
+	SuccessThreshold int
+	// IsFailure tells if a request failed. By default only errors count, an
+	// error response proves the node is up. Response is nil for batches.
+	IsFailure func(response rpc.Response, err error) bool
+	// OnStateChange is called on every state change, from the goroutine of
+	// the request causing it.
+	OnStateChange func(from, to CircuitState)
+}
+
+// CircuitBreaker stops sending requests to a provider after consecutive
+// failures. Once CoolDown elapsed, the next request probes the provider with
+// IsConnected. If it answers, the circuit is half open and requests are let
+// through one at a time until SuccessThreshold of them succeed. Any failure
+// opens the circuit again.
+type CircuitBreaker struct {
+	option CircuitBreakerOption
+
+	lock      sync.Mutex
+	state     CircuitState
+	failures  int
+	successes int
+	openedAt  time.Time
+	probing   bool
+	trial     bool
+}
+
+// NewCircuitBreaker creates a closed circuit breaker. It is meant to wrap a
+// single provider.
+func NewCircuitBreaker(option *CircuitBreakerOption) *CircuitBreaker {
+	b := &CircuitBreaker{}
+	if option != nil {
+		b.option = *option
+	}
+	if b.option.FailureThreshold <= 0 {
+		b.option.FailureThreshold = breakerFailureThreshold
+	}
+	if b.option.CoolDown <= 0 {
+		b.option.CoolDown = breakerCoolDown
+	}
+	if b.option.SuccessThreshold <= 0 {
+		b.option.SuccessThreshold = 1
+	}
+	if b.option.IsFailure == nil {
+		b.option.IsFailure = func(response rpc.Response, err error) bool {
+			return err != nil
+		}
+	}
+	return b
+}
+
+// State returns the current state.
+func (b *CircuitBreaker) State() CircuitState {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.state
+}
+
+// Wrap returns next guarded by the circuit breaker, it is a Middleware.
+func (b *CircuitBreaker) Wrap(next Provider) Provider {
+	p := &breakerProvider{breaker: b, next: next}
+	if batchProvider, ok := next.(BatchProvider); ok {
+		return &breakerBatchProvider{breakerProvider: p, nextBatch: batchProvider}
+	}
+	return p
+}
+
+// allow tells if a request can be sent, probing next if the cool down
+// elapsed.
+func (b *CircuitBreaker) allow(next Provider) error {
+	b.lock.Lock()
+	switch b.state {
+	case CircuitClosed:
+		b.lock.Unlock()
+		return nil
+	case CircuitHalfOpen:
+		defer b.lock.Unlock()
+		if b.trial {
+			return ErrCircuitOpen
+		}
+		b.trial = true
+		return nil
+	}
+
+	if b.probing || time.Since(b.openedAt) < b.option.CoolDown {
+		b.lock.Unlock()
+		return ErrCircuitOpen
+	}
+	b.probing = true
+	b.lock.Unlock()
+
+	connected := next.IsConnected()
+
+	b.lock.Lock()
+	b.probing = false
+	if !connected {
+		b.openedAt = time.Now()
+		b.lock.Unlock()
+		return ErrCircuitOpen
+	}
+	b.trial = true
+	b.successes = 0
+	notify := b.transition(CircuitHalfOpen)
+	b.lock.Unlock()
+	notify()
+	return nil
+}
+
+// record counts the outcome of a request let through.
+func (b *CircuitBreaker) record(failed bool) {
+	b.lock.Lock()
+	notify := func() {}
+	switch b.state {
+	case CircuitClosed:
+		if !failed {
+			b.failures = 0
+		} else if b.failures++; b.failures >= b.option.FailureThreshold {
+			notify = b.open()
+		}
+	case CircuitHalfOpen:
+		b.trial = false
+		if failed {
+			notify = b.open()
+		} else if b.successes++; b.successes >= b.option.SuccessThreshold {
+			b.failures = 0
+			notify = b.transition(CircuitClosed)
+		}
+	}
+	b.lock.Unlock()
+	notify()
+}
+
+func (b *CircuitBreaker) open() func() {
+	b.openedAt = time.Now()
+	return b.transition(CircuitOpen)
+}
+
+// transition changes the state and returns the notification to send once
+// the lock is released.
+func (b *CircuitBreaker) transition(to CircuitState) func() {
+	from := b.state
+	b.state = to
+	if b.option.OnStateChange == nil || from == to {
+		return func() {}
+	}
+	return func() { b.option.OnStateChange(from, to) }
+}
+
+type breakerProvider struct {
+	breaker *CircuitBreaker
+	next    Provider
+}
+
+// IsConnected is false while the circuit is open, otherwise it asks the next
+// provider.
+func (provider *breakerProvider) IsConnected() bool {
+	if provider.breaker.State() == CircuitOpen {
+		return false
+	}
+	return provider.next.IsConnected()
+}
+
+// Send sends request unless the circuit is open.
+func (provider *breakerProvider) Send(request rpc.Request) (rpc.Response, error) {
+	if err := provider.breaker.allow(provider.next); err != nil {
+		return nil, err
+	}
+	response, err := provider.next.Send(request)
+	provider.breaker.record(provider.breaker.option.IsFailure(response, err))
+	return response, err
+}
+
+func (provider *breakerProvider) GetRPCMethod() rpc.RPC {
+	return provider.next.GetRPCMethod()
+}
+
+type breakerBatchProvider struct {
+	*breakerProvider
+	nextBatch BatchProvider
+}
+
+// SendBatch sends requests unless the circuit is open. The batch counts as a
+// single request.
+func (provider *breakerBatchProvider) SendBatch(requests []rpc.Request) ([]rpc.Response, error) {
+	if err := provider.breaker.allow(provider.next); err != nil {
+		return nil, err
+	}
+	responses, err := provider.nextBatch.SendBatch(requests)
+	provider.breaker.record(provider.breaker.option.IsFailure(nil, err))
+	return responses, err
+}
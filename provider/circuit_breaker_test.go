@@ -0,0 +1,171 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alanchchen/web3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type CircuitBreakerTestSuite struct {
+	suite.Suite
+	node    *testNode
+	breaker *CircuitBreaker
+	p       Provider
+
+	lock    sync.Mutex
+	changes []string
+}
+
+func (suite *CircuitBreakerTestSuite) send() error {
+	_, err := suite.p.Send(suite.p.GetRPCMethod().NewRequest("eth_blockNumber"))
+	return err
+}
+
+func (suite *CircuitBreakerTestSuite) Test_Open() {
+	suite.node.down = true
+	for i := 0; i < 3; i++ {
+		assert.Error(suite.T(), suite.send(), "Should be error")
+	}
+	assert.Equal(suite.T(), CircuitOpen, suite.breaker.State(), "Should be equal")
+	assert.Equal(suite.T(), ErrCircuitOpen, suite.send(), "Should be equal")
+	assert.Equal(suite.T(), 3, suite.node.count("eth_blockNumber"), "Should be equal")
+	assert.False(suite.T(), suite.p.IsConnected(), "Should be false")
+	assert.Equal(suite.T(), []string{"closed -> open"}, suite.changes, "Should be equal")
+}
+
+func (suite *CircuitBreakerTestSuite) Test_SuccessResets() {
+	suite.node.down = true
+	assert.Error(suite.T(), suite.send(), "Should be error")
+	assert.Error(suite.T(), suite.send(), "Should be error")
+	suite.node.down = false
+	assert.NoError(suite.T(), suite.send(), "Should be no error")
+	suite.node.down = true
+	assert.Error(suite.T(), suite.send(), "Should be error")
+	assert.Error(suite.T(), suite.send(), "Should be error")
+	assert.Equal(suite.T(), CircuitClosed, suite.breaker.State(), "Should be equal")
+}
+
+func (suite *CircuitBreakerTestSuite) Test_ErrorResponses() {
+	stub := answering(nil, &rpc.JSONRPCError{Code: rpc.CodeInternalError, Message: "Boom"})
+	p := suite.breaker.Wrap(stub)
+	for i := 0; i < 5; i++ {
+		_, err := p.Send(p.GetRPCMethod().NewRequest("eth_call"))
+		assert.NoError(suite.T(), err, "Should be no error")
+	}
+	assert.Equal(suite.T(), CircuitClosed, suite.breaker.State(), "Should be equal")
+}
+
+func (suite *CircuitBreakerTestSuite) Test_Recover() {
+	suite.node.down = true
+	for i := 0; i < 3; i++ {
+		suite.send()
+	}
+
+	// The probe fails while the node is disconnected.
+	suite.node.connected = false
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(suite.T(), ErrCircuitOpen, suite.send(), "Should be equal")
+	assert.Equal(suite.T(), CircuitOpen, suite.breaker.State(), "Should be equal")
+
+	suite.node.connected = true
+	suite.node.down = false
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(suite.T(), suite.send(), "Should be no error")
+	assert.Equal(suite.T(), CircuitHalfOpen, suite.breaker.State(), "Should be equal")
+	assert.NoError(suite.T(), suite.send(), "Should be no error")
+	assert.Equal(suite.T(), CircuitClosed, suite.breaker.State(), "Should be equal")
+	assert.Equal(suite.T(), []string{"closed -> open", "open -> half-open", "half-open -> closed"}, suite.changes, "Should be equal")
+}
+
+func (suite *CircuitBreakerTestSuite) Test_HalfOpenFailure() {
+	suite.node.down = true
+	for i := 0; i < 3; i++ {
+		suite.send()
+	}
+	time.Sleep(20 * time.Millisecond)
+	assert.Error(suite.T(), suite.send(), "Should be error")
+	assert.Equal(suite.T(), CircuitOpen, suite.breaker.State(), "Should be equal")
+	assert.Equal(suite.T(), ErrCircuitOpen, suite.send(), "Should be equal")
+}
+
+func (suite *CircuitBreakerTestSuite) Test_HalfOpenTrial() {
+	suite.node.down = true
+	for i := 0; i < 3; i++ {
+		suite.send()
+	}
+	suite.node.down = false
+	suite.node.delay = 20 * time.Millisecond
+	time.Sleep(20 * time.Millisecond)
+
+	done := make(chan error)
+	go func() { done <- suite.send() }()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(suite.T(), ErrCircuitOpen, suite.send(), "Should be equal")
+	assert.NoError(suite.T(), <-done, "Should be no error")
+}
+
+func (suite *CircuitBreakerTestSuite) Test_Batch() {
+	failure := errors.New("Connection refused")
+	stub := &stubBatchProvider{stubProvider: answering(nil, failure)}
+	p := suite.breaker.Wrap(stub).(BatchProvider)
+	for i := 0; i < 3; i++ {
+		_, err := p.SendBatch([]rpc.Request{p.GetRPCMethod().NewRequest("eth_call")})
+		assert.Equal(suite.T(), failure, err, "Should be equal")
+	}
+	_, err := p.SendBatch([]rpc.Request{p.GetRPCMethod().NewRequest("eth_call")})
+	assert.Equal(suite.T(), ErrCircuitOpen, err, "Should be equal")
+}
+
+func (suite *CircuitBreakerTestSuite) SetupTest() {
+	suite.changes = nil
+	suite.node = newTestNode("a", 1)
+	suite.breaker = NewCircuitBreaker(&CircuitBreakerOption{
+		FailureThreshold: 3,
+		CoolDown:         10 * time.Millisecond,
+		SuccessThreshold: 2,
+		OnStateChange: func(from, to CircuitState) {
+			suite.lock.Lock()
+			defer suite.lock.Unlock()
+			suite.changes = append(suite.changes, from.String()+" -> "+to.String())
+		},
+	})
+	suite.p = Chain(suite.node, suite.breaker.Wrap)
+}
+
+func Test_CircuitBreakerTestSuite(t *testing.T) {
+	suite.Run(t, new(CircuitBreakerTestSuite))
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/alanchchen/web3go/rpc"
+)
+
+// CoalesceOption configures the Coalesce middleware.
+type CoalesceOption struct {
+	// Methods are the methods coalesced, DefaultSafeMethods if nil. Methods
+	// with side effects, such as installing a filter, must not be listed as
+	// all callers would share the same effect.
+	Methods []string
+}
+
+// Coalesce returns a middleware merging identical requests in flight, with
+// the same method and parameters, into a single request. Every caller gets
+// a copy of the response carrying the id of its request. Batches are sent
+// as they are.
+func Coalesce(option *CoalesceOption) Middleware {
+	methods := DefaultSafeMethods
+	if option != nil && option.Methods != nil {
+		methods = option.Methods
+	}
+	safe := make(map[string]bool)
+	for _, method := range methods {
+		safe[method] = true
+	}
+
+	// Each provider gets its own flights, so calls to different backends are
+	// never joined.
+	return func(next Provider) Provider {
+		c := &coalescer{methods: safe, calls: make(map[string]*flight)}
+		method := next.GetRPCMethod()
+		send := func(request rpc.Request, next SendFunc) (rpc.Response, error) {
+			return c.send(method, request, next)
+		}
+		return Intercept(send, nil)(next)
+	}
+}
+
+// flight is a request in flight shared by several callers.
+type flight struct {
+	done     chan struct{}
+	response rpc.Response
+	err      error
+}
+
+type coalescer struct {
+	methods map[string]bool
+
+	lock  sync.Mutex
+	calls map[string]*flight
+}
+
+func (c *coalescer) send(method rpc.RPC, request rpc.Request, next SendFunc) (rpc.Response, error) {
+	name, _ := request.Get("method").(string)
+	if !c.methods[name] {
+		return next(request)
+	}
+	params, err := json.Marshal(requestParams(request))
+	if err != nil {
+		return next(request)
+	}
+	key := name + string(params)
+
+	c.lock.Lock()
+	if f, ok := c.calls[key]; ok {
+		c.lock.Unlock()
+		<-f.done
+		if f.err != nil {
+			return nil, f.err
+		}
+		return copyResponse(method, f.response, request.ID()), nil
+	}
+	f := &flight{done: make(chan struct{})}
+	c.calls[key] = f
+	c.lock.Unlock()
+
+	f.response, f.err = next(request)
+
+	c.lock.Lock()
+	delete(c.calls, key)
+	c.lock.Unlock()
+	close(f.done)
+	return f.response, f.err
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alanchchen/web3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type CoalesceTestSuite struct {
+	suite.Suite
+	release chan struct{}
+	result  interface{}
+	err     error
+	stub    *stubProvider
+	p       Provider
+}
+
+// concurrently sends count requests at once, the backend answers once all
+// of them are sent.
+func (suite *CoalesceTestSuite) concurrently(count int, method string, params ...interface{}) ([]rpc.Request, []rpc.Response, []error) {
+	requests := make([]rpc.Request, count)
+	responses := make([]rpc.Response, count)
+	errs := make([]error, count)
+	var wg sync.WaitGroup
+	for i := range requests {
+		requests[i] = suite.p.GetRPCMethod().NewRequest(method)
+		requests[i].Set("params", params)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i], errs[i] = suite.p.Send(requests[i])
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(suite.release)
+	wg.Wait()
+	return requests, responses, errs
+}
+
+func (suite *CoalesceTestSuite) Test_Coalesce() {
+	requests, responses, errs := suite.concurrently(5, "eth_getBlockByNumber", "latest", false)
+	assert.Equal(suite.T(), 1, suite.stub.count("eth_getBlockByNumber"), "Should be equal")
+	for i := range requests {
+		assert.NoError(suite.T(), errs[i], "Should be no error")
+		assert.Equal(suite.T(), requests[i].ID(), responses[i].ID(), "Should be equal")
+		assert.Equal(suite.T(), map[string]interface{}{"number": "0x10"}, responses[i].Get("result"), "Should be equal")
+	}
+}
+
+func (suite *CoalesceTestSuite) Test_DifferentParams() {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		suite.p.Send(suite.p.GetRPCMethod().NewRequest("eth_getBlockByNumber", "0x1"))
+	}()
+	suite.concurrently(2, "eth_getBlockByNumber", "latest", false)
+	<-done
+	assert.Equal(suite.T(), 2, suite.stub.count("eth_getBlockByNumber"), "Should be equal")
+}
+
+func (suite *CoalesceTestSuite) Test_UnsafeMethods() {
+	suite.concurrently(3, "eth_newBlockFilter")
+	assert.Equal(suite.T(), 3, suite.stub.count("eth_newBlockFilter"), "Should be equal")
+}
+
+func (suite *CoalesceTestSuite) Test_Errors() {
+	suite.err = &rpc.JSONRPCError{Code: rpc.CodeInternalError, Message: "Boom"}
+	requests, responses, errs := suite.concurrently(3, "eth_blockNumber")
+	for i := range requests {
+		assert.NoError(suite.T(), errs[i], "Should be no error")
+		assert.Equal(suite.T(), requests[i].ID(), responses[i].ID(), "Should be equal")
+		assert.EqualError(suite.T(), responses[i].Error(), "Boom", "Should be equal")
+	}
+
+	suite.release = make(chan struct{})
+	suite.err = errors.New("Connection refused")
+	_, _, errs = suite.concurrently(3, "eth_blockNumber")
+	assert.Equal(suite.T(), []error{suite.err, suite.err, suite.err}, errs, "Should be equal")
+	assert.Equal(suite.T(), 2, suite.stub.count("eth_blockNumber"), "Should be equal")
+}
+
+func (suite *CoalesceTestSuite) Test_Backends() {
+	other := newStubProvider(func(request rpc.Request) (interface{}, error) {
+		<-suite.release
+		return map[string]interface{}{"number": "0x20"}, nil
+	})
+	coalesce := Coalesce(nil)
+	first, second := Chain(suite.stub, coalesce), Chain(other, coalesce)
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 2)
+	for i, p := range []Provider{first, second} {
+		wg.Add(1)
+		go func(i int, p Provider) {
+			defer wg.Done()
+			response, err := p.Send(p.GetRPCMethod().NewRequest("eth_blockNumber"))
+			if assert.NoError(suite.T(), err, "Should be no error") {
+				results[i] = response.Get("result")
+			}
+		}(i, p)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(suite.release)
+	wg.Wait()
+
+	assert.Equal(suite.T(), 1, suite.stub.count("eth_blockNumber"), "Should be equal")
+	assert.Equal(suite.T(), 1, other.count("eth_blockNumber"), "Should be equal")
+	assert.Equal(suite.T(), map[string]interface{}{"number": "0x10"}, results[0], "Should be equal")
+	assert.Equal(suite.T(), map[string]interface{}{"number": "0x20"}, results[1], "Should be equal")
+}
+
+func (suite *CoalesceTestSuite) Test_Sequential() {
+	close(suite.release)
+	for i := 0; i < 2; i++ {
+		_, err := suite.p.Send(suite.p.GetRPCMethod().NewRequest("eth_blockNumber"))
+		assert.NoError(suite.T(), err, "Should be no error")
+	}
+	assert.Equal(suite.T(), 2, suite.stub.count("eth_blockNumber"), "Should be equal")
+}
+
+func (suite *CoalesceTestSuite) SetupTest() {
+	suite.release = make(chan struct{})
+	suite.err = nil
+	suite.stub = newStubProvider(func(request rpc.Request) (interface{}, error) {
+		<-suite.release
+		if suite.err != nil {
+			return nil, suite.err
+		}
+		return map[string]interface{}{"number": "0x10"}, nil
+	})
+	suite.p = Chain(suite.stub, Coalesce(nil))
+}
+
+func Test_CoalesceTestSuite(t *testing.T) {
+	suite.Run(t, new(CoalesceTestSuite))
+}
@@ -0,0 +1,472 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alanchchen/web3go/rpc"
+)
+
+var (
+	// ErrNoProviders is returned by a FailoverProvider without providers.
+	ErrNoProviders = errors.New("No providers available")
+)
+
+const (
+	healthCheckInterval = 10 * time.Second
+	maxBlockLag         = 5
+	// latencyWeight is the weight of a new sample in the latency average.
+	latencyWeight = 0.2
+)
+
+// Strategy decides which healthy provider gets a request.
+type Strategy int
+
+const (
+	// RoundRobin spreads requests over healthy providers in turn.
+	RoundRobin Strategy = iota
+	// LeastLatency sends requests to the fastest healthy provider.
+	LeastLatency
+)
+
+// FailoverOption configures a FailoverProvider. Zero fields take defaults.
+type FailoverOption struct {
+	Strategy Strategy
+	// HealthCheckInterval is the delay between two health checks, 10s by
+	// default.
+	HealthCheckInterval time.Duration
+	// MaxBlockLag is the number of blocks a provider may be behind the
+	// highest one and still be healthy, 5 by default.
+	MaxBlockLag uint64
+	// SafeMethods are the methods sent to another provider when the node may
+	// have processed the request, DefaultSafeMethods if nil.
+	SafeMethods []string
+}
+
+func (opt *FailoverOption) withDefaults() FailoverOption {
+	result := FailoverOption{}
+	if opt != nil {
+		result = *opt
+	}
+	if result.HealthCheckInterval <= 0 {
+		result.HealthCheckInterval = healthCheckInterval
+	}
+	if result.MaxBlockLag == 0 {
+		result.MaxBlockLag = maxBlockLag
+	}
+	if result.SafeMethods == nil {
+		result.SafeMethods = DefaultSafeMethods
+	}
+	return result
+}
+
+// filterCreators are the methods installing a filter.
+var filterCreators = map[string]bool{
+	"eth_newFilter":                   true,
+	"eth_newBlockFilter":              true,
+	"eth_newPendingTransactionFilter": true,
+}
+
+// filterMethods are the methods taking a filter id.
+var filterMethods = map[string]bool{
+	"eth_getFilterChanges": true,
+	"eth_getFilterLogs":    true,
+	"eth_uninstallFilter":  true,
+}
+
+type backend struct {
+	provider Provider
+
+	lock    sync.Mutex
+	healthy bool
+	height  uint64
+	latency time.Duration
+}
+
+func (b *backend) send(request rpc.Request) (rpc.Response, error) {
+	start := time.Now()
+	response, err := b.provider.Send(request)
+	if err != nil {
+		b.fail()
+	} else {
+		b.measure(time.Since(start))
+	}
+	return response, err
+}
+
+func (b *backend) sendBatch(requests []rpc.Request) ([]rpc.Response, error) {
+	if batchProvider, ok := b.provider.(BatchProvider); ok {
+		start := time.Now()
+		responses, err := batchProvider.SendBatch(requests)
+		if err != nil {
+			b.fail()
+		} else {
+			b.measure(time.Since(start))
+		}
+		return responses, err
+	}
+
+	responses := make([]rpc.Response, len(requests))
+	for i, request := range requests {
+		response, err := b.send(request)
+		if err != nil {
+			return nil, err
+		}
+		responses[i] = response
+	}
+	return responses, nil
+}
+
+func (b *backend) measure(sample time.Duration) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.latency == 0 {
+		b.latency = sample
+	} else {
+		b.latency = time.Duration((1-latencyWeight)*float64(b.latency) + latencyWeight*float64(sample))
+	}
+}
+
+// fail marks the backend unhealthy until the next health check.
+func (b *backend) fail() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.healthy = false
+}
+
+func (b *backend) state() (healthy bool, latency time.Duration) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.healthy, b.latency
+}
+
+// stickyFilter is a filter installed on a backend, known to callers by a
+// local id as filter ids of different nodes collide.
+type stickyFilter struct {
+	backend *backend
+	id      interface{}
+}
+
+// FailoverProvider spreads requests over several providers. Providers are
+// checked periodically with IsConnected and eth_blockNumber, those which are
+// disconnected or lag behind the highest block are skipped until they
+// recover. A request failing on a provider is sent to the next one if it is
+// safe to send again.
+//
+// Filters stay on the provider that installed them. If that provider fails,
+// filter requests are answered with "filter not found", so callers install
+// the filter again.
+type FailoverProvider struct {
+	option   FailoverOption
+	backends []*backend
+	safe     map[string]bool
+	next     uint64
+
+	filtersLock sync.Mutex
+	filters     map[string]*stickyFilter
+	lastFilter  uint64
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewFailoverProvider creates a provider over providers, all considered
+// healthy until the first health check. Close stops the health checks.
+func NewFailoverProvider(providers []Provider, option *FailoverOption) *FailoverProvider {
+	f := &FailoverProvider{
+		option:  option.withDefaults(),
+		safe:    make(map[string]bool),
+		filters: make(map[string]*stickyFilter),
+		stopCh:  make(chan struct{}),
+	}
+	for _, p := range providers {
+		f.backends = append(f.backends, &backend{provider: p, healthy: true})
+	}
+	for _, method := range f.option.SafeMethods {
+		f.safe[method] = true
+	}
+	for method := range filterCreators {
+		f.safe[method] = true
+	}
+
+	go f.checkLoop()
+	return f
+}
+
+func (f *FailoverProvider) checkLoop() {
+	ticker := time.NewTicker(f.option.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.stopCh:
+			return
+		case <-ticker.C:
+			f.CheckHealth()
+		}
+	}
+}
+
+// CheckHealth checks all providers now.
+func (f *FailoverProvider) CheckHealth() {
+	type check struct {
+		ok     bool
+		height uint64
+	}
+	checks := make([]check, len(f.backends))
+	var wg sync.WaitGroup
+	for i, b := range f.backends {
+		wg.Add(1)
+		go func(i int, b *backend) {
+			defer wg.Done()
+			if !b.provider.IsConnected() {
+				return
+			}
+			response, err := b.send(b.provider.GetRPCMethod().NewRequest("eth_blockNumber"))
+			if err != nil || response.Error() != nil {
+				return
+			}
+			result, _ := response.Get("result").(string)
+			height, err := strconv.ParseUint(strings.TrimPrefix(result, "0x"), 16, 64)
+			checks[i] = check{ok: err == nil, height: height}
+		}(i, b)
+	}
+	wg.Wait()
+
+	var highest uint64
+	for _, c := range checks {
+		if c.ok && c.height > highest {
+			highest = c.height
+		}
+	}
+	for i, b := range f.backends {
+		b.lock.Lock()
+		b.healthy = checks[i].ok && highest-checks[i].height <= f.option.MaxBlockLag
+		b.height = checks[i].height
+		b.lock.Unlock()
+	}
+}
+
+// Close stops the health checks.
+func (f *FailoverProvider) Close() {
+	f.stopOnce.Do(func() {
+		close(f.stopCh)
+	})
+}
+
+// IsConnected tells if any provider is connected.
+func (f *FailoverProvider) IsConnected() bool {
+	for _, b := range f.backends {
+		if b.provider.IsConnected() {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRPCMethod returns the RPC method of the first provider.
+func (f *FailoverProvider) GetRPCMethod() rpc.RPC {
+	if len(f.backends) == 0 {
+		return rpc.GetDefaultMethod()
+	}
+	return f.backends[0].provider.GetRPCMethod()
+}
+
+// Send sends request to a healthy provider, failing over to the others.
+func (f *FailoverProvider) Send(request rpc.Request) (rpc.Response, error) {
+	method, _ := request.Get("method").(string)
+	if filterMethods[method] {
+		return f.sendFilter(method, request)
+	}
+
+	var response rpc.Response
+	var err error
+	for _, b := range f.candidates() {
+		if response, err = b.send(request); err == nil {
+			if filterCreators[method] {
+				return f.stick(b, response), nil
+			}
+			return response, nil
+		}
+		if !f.safe[method] && !notProcessed(err) {
+			return nil, err
+		}
+	}
+	if err == nil {
+		err = ErrNoProviders
+	}
+	return nil, err
+}
+
+// SendBatch sends requests to a healthy provider in one round trip if it
+// can batch, failing over to the others. Batches with filter requests are
+// sent one request at a time.
+func (f *FailoverProvider) SendBatch(requests []rpc.Request) ([]rpc.Response, error) {
+	safe := true
+	for _, request := range requests {
+		method, _ := request.Get("method").(string)
+		if filterCreators[method] || filterMethods[method] {
+			return f.sendEach(requests)
+		}
+		safe = safe && f.safe[method]
+	}
+
+	var responses []rpc.Response
+	var err error
+	for _, b := range f.candidates() {
+		if responses, err = b.sendBatch(requests); err == nil {
+			return responses, nil
+		}
+		if !safe && !notProcessed(err) {
+			return nil, err
+		}
+	}
+	if err == nil {
+		err = ErrNoProviders
+	}
+	return nil, err
+}
+
+func (f *FailoverProvider) sendEach(requests []rpc.Request) ([]rpc.Response, error) {
+	responses := make([]rpc.Response, len(requests))
+	for i, request := range requests {
+		response, err := f.Send(request)
+		if err != nil {
+			return nil, err
+		}
+		responses[i] = response
+	}
+	return responses, nil
+}
+
+// candidates returns the healthy backends in the order of the strategy,
+// followed by the unhealthy ones as a last resort.
+func (f *FailoverProvider) candidates() []*backend {
+	type candidate struct {
+		backend *backend
+		latency time.Duration
+	}
+	var healthy []candidate
+	var unhealthy []*backend
+	for _, b := range f.backends {
+		if ok, latency := b.state(); ok {
+			healthy = append(healthy, candidate{backend: b, latency: latency})
+		} else {
+			unhealthy = append(unhealthy, b)
+		}
+	}
+
+	if len(healthy) > 0 {
+		switch f.option.Strategy {
+		case LeastLatency:
+			sort.SliceStable(healthy, func(i, j int) bool { return healthy[i].latency < healthy[j].latency })
+		default:
+			first := int(atomic.AddUint64(&f.next, 1)-1) % len(healthy)
+			healthy = append(healthy[first:], healthy[:first]...)
+		}
+	}
+
+	result := make([]*backend, 0, len(f.backends))
+	for _, c := range healthy {
+		result = append(result, c.backend)
+	}
+	return append(result, unhealthy...)
+}
+
+// stick records the filter installed on b and answers with its local id.
+func (f *FailoverProvider) stick(b *backend, response rpc.Response) rpc.Response {
+	if response.Error() != nil {
+		return response
+	}
+
+	f.filtersLock.Lock()
+	f.lastFilter++
+	id := fmt.Sprintf("0x%x", f.lastFilter)
+	f.filters[id] = &stickyFilter{backend: b, id: response.Get("result")}
+	f.filtersLock.Unlock()
+
+	return newResponse(b.provider.GetRPCMethod(), response.ID(), id, nil)
+}
+
+// sendFilter sends a filter request to the provider of the filter.
+func (f *FailoverProvider) sendFilter(method string, request rpc.Request) (rpc.Response, error) {
+	params := requestParams(request)
+	var id string
+	if len(params) > 0 {
+		id, _ = params[0].(string)
+	}
+	id = strings.ToLower(id)
+
+	f.filtersLock.Lock()
+	filter, ok := f.filters[id]
+	if ok && method == "eth_uninstallFilter" {
+		delete(f.filters, id)
+	}
+	f.filtersLock.Unlock()
+
+	if !ok {
+		if method == "eth_uninstallFilter" {
+			return newResponse(f.GetRPCMethod(), request.ID(), false, nil), nil
+		}
+		return f.filterNotFound(request), nil
+	}
+
+	// The request may be in use elsewhere, e.g. by a quorum, a copy is sent
+	// with the id of the backend.
+	forwarded := filter.backend.provider.GetRPCMethod().NewRequest(method, append([]interface{}{filter.id}, params[1:]...)...)
+	response, err := filter.backend.send(forwarded)
+	if err != nil || isFilterNotFound(response.Error()) {
+		f.filtersLock.Lock()
+		delete(f.filters, id)
+		f.filtersLock.Unlock()
+	}
+	if err != nil {
+		if method == "eth_uninstallFilter" {
+			return nil, err
+		}
+		return f.filterNotFound(request), nil
+	}
+	return copyResponse(f.GetRPCMethod(), response, request.ID()), nil
+}
+
+func isFilterNotFound(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "filter not found")
+}
+
+func (f *FailoverProvider) filterNotFound(request rpc.Request) rpc.Response {
+	return newResponse(f.GetRPCMethod(), request.ID(), nil, &rpc.JSONRPCError{Code: codeServerError, Message: "filter not found"})
+}
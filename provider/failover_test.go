@@ -0,0 +1,249 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alanchchen/web3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// testNode simulates a node at a given height, with a single filter "0x1".
+// Requests fail with a network error while it is down.
+type testNode struct {
+	*stubProvider
+	name   string
+	height uint64
+	delay  time.Duration
+	down   bool
+}
+
+func newTestNode(name string, height uint64) *testNode {
+	n := &testNode{name: name, height: height}
+	n.stubProvider = newStubProvider(n.handle)
+	return n
+}
+
+func (n *testNode) handle(request rpc.Request) (interface{}, error) {
+	time.Sleep(n.delay)
+	if n.down {
+		return nil, &net.OpError{Op: "read", Err: errors.New("Connection reset")}
+	}
+	switch request.Get("method").(string) {
+	case "eth_blockNumber":
+		return fmt.Sprintf("0x%x", n.height), nil
+	case "eth_newBlockFilter":
+		return "0x1", nil
+	case "eth_getFilterChanges", "eth_uninstallFilter":
+		if requestParams(request)[0] != "0x1" {
+			return nil, &rpc.JSONRPCError{Code: codeServerError, Message: "filter not found"}
+		}
+	}
+	return n.name, nil
+}
+
+type FailoverTestSuite struct {
+	suite.Suite
+	a, b, c  *testNode
+	provider *FailoverProvider
+}
+
+func (suite *FailoverTestSuite) send(method string, params ...interface{}) (interface{}, error) {
+	request := suite.provider.GetRPCMethod().NewRequest(method)
+	if len(params) > 0 {
+		request.Set("params", params)
+	}
+	response, err := suite.provider.Send(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.Error() != nil {
+		return nil, response.Error()
+	}
+	assert.Equal(suite.T(), request.ID(), response.ID(), "Should be equal")
+	return response.Get("result"), nil
+}
+
+func (suite *FailoverTestSuite) Test_RoundRobin() {
+	seen := make(map[interface{}]int)
+	for i := 0; i < 6; i++ {
+		result, err := suite.send("eth_gasPrice")
+		assert.NoError(suite.T(), err, "Should be no error")
+		seen[result]++
+	}
+	assert.Equal(suite.T(), map[interface{}]int{"a": 2, "b": 2, "c": 2}, seen, "Should be equal")
+}
+
+func (suite *FailoverTestSuite) Test_LeastLatency() {
+	suite.provider.option.Strategy = LeastLatency
+	suite.a.delay = 20 * time.Millisecond
+	suite.c.delay = 10 * time.Millisecond
+	suite.provider.CheckHealth()
+
+	for i := 0; i < 3; i++ {
+		result, err := suite.send("eth_gasPrice")
+		assert.NoError(suite.T(), err, "Should be no error")
+		assert.Equal(suite.T(), "b", result, "Should be equal")
+	}
+}
+
+func (suite *FailoverTestSuite) Test_HealthCheck() {
+	suite.a.height = 90
+	suite.b.connected = false
+	suite.provider.CheckHealth()
+
+	for i := 0; i < 3; i++ {
+		result, err := suite.send("eth_gasPrice")
+		assert.NoError(suite.T(), err, "Should be no error")
+		assert.Equal(suite.T(), "c", result, "Should be equal")
+	}
+	assert.True(suite.T(), suite.provider.IsConnected(), "Should be true")
+
+	suite.a.height = 98
+	suite.b.connected = true
+	suite.provider.CheckHealth()
+	seen := make(map[interface{}]bool)
+	for i := 0; i < 3; i++ {
+		result, _ := suite.send("eth_gasPrice")
+		seen[result] = true
+	}
+	assert.Len(suite.T(), seen, 3, "Should be equal")
+}
+
+func (suite *FailoverTestSuite) Test_Failover() {
+	suite.a.down = true
+	suite.b.down = true
+	for i := 0; i < 3; i++ {
+		result, err := suite.send("eth_gasPrice")
+		assert.NoError(suite.T(), err, "Should be no error")
+		assert.Equal(suite.T(), "c", result, "Should be equal")
+	}
+	// Failed providers are skipped until they recover.
+	assert.Equal(suite.T(), 1, suite.a.count("eth_gasPrice"), "Should be equal")
+	assert.Equal(suite.T(), 1, suite.b.count("eth_gasPrice"), "Should be equal")
+
+	suite.c.down = true
+	_, err := suite.send("eth_gasPrice")
+	assert.Error(suite.T(), err, "Should be error")
+}
+
+func (suite *FailoverTestSuite) Test_UnsafeMethod() {
+	suite.a.down = true
+	_, err := suite.send("eth_sendRawTransaction", "0x00")
+	assert.Error(suite.T(), err, "Should be error")
+	assert.Equal(suite.T(), 0, suite.b.count("eth_sendRawTransaction")+suite.c.count("eth_sendRawTransaction"), "Should be equal")
+}
+
+func (suite *FailoverTestSuite) Test_StickyFilters() {
+	first, err := suite.send("eth_newBlockFilter")
+	assert.NoError(suite.T(), err, "Should be no error")
+	second, err := suite.send("eth_newBlockFilter")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.NotEqual(suite.T(), first, second, "Should have distinct ids")
+
+	for i := 0; i < 3; i++ {
+		result, err := suite.send("eth_getFilterChanges", first)
+		assert.NoError(suite.T(), err, "Should be no error")
+		assert.Equal(suite.T(), "a", result, "Should be equal")
+		result, err = suite.send("eth_getFilterChanges", second)
+		assert.NoError(suite.T(), err, "Should be no error")
+		assert.Equal(suite.T(), "b", result, "Should be equal")
+	}
+
+	suite.a.down = true
+	_, err = suite.send("eth_getFilterChanges", first)
+	assert.EqualError(suite.T(), err, "filter not found", "Should be equal")
+	assert.Equal(suite.T(), 0, suite.c.count("eth_getFilterChanges"), "Should be equal")
+
+	result, err := suite.send("eth_uninstallFilter", second)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), "b", result, "Should be equal")
+	_, err = suite.send("eth_getFilterChanges", second)
+	assert.EqualError(suite.T(), err, "filter not found", "Should be equal")
+}
+
+func (suite *FailoverTestSuite) Test_SharedFilterRequest() {
+	id, err := suite.send("eth_newBlockFilter")
+	assert.NoError(suite.T(), err, "Should be no error")
+
+	// The same request sent concurrently, like a quorum does.
+	request := suite.provider.GetRPCMethod().NewRequest("eth_getFilterChanges", id)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, err := suite.provider.Send(request)
+			if assert.NoError(suite.T(), err, "Should be no error") {
+				assert.Equal(suite.T(), request.ID(), response.ID(), "Should be equal")
+				assert.NoError(suite.T(), response.Error(), "Should be no error")
+			}
+			assert.Equal(suite.T(), []interface{}{id}, requestParams(request), "Should be untouched")
+		}()
+	}
+	wg.Wait()
+}
+
+func (suite *FailoverTestSuite) Test_Batch() {
+	batch := &stubBatchProvider{stubProvider: suite.a.stubProvider}
+	p := NewFailoverProvider([]Provider{batch, suite.b}, &FailoverOption{HealthCheckInterval: time.Hour})
+	defer p.Close()
+
+	method := p.GetRPCMethod()
+	for i := 0; i < 2; i++ {
+		responses, err := p.SendBatch([]rpc.Request{method.NewRequest("eth_gasPrice"), method.NewRequest("eth_blockNumber")})
+		assert.NoError(suite.T(), err, "Should be no error")
+		assert.Len(suite.T(), responses, 2, "Should be equal")
+	}
+	assert.Equal(suite.T(), 1, batch.batches, "Should be equal")
+	assert.Equal(suite.T(), 1, suite.b.count("eth_gasPrice"), "Should be equal")
+}
+
+func (suite *FailoverTestSuite) SetupTest() {
+	suite.a = newTestNode("a", 100)
+	suite.b = newTestNode("b", 100)
+	suite.c = newTestNode("c", 99)
+	suite.provider = NewFailoverProvider([]Provider{suite.a, suite.b, suite.c}, &FailoverOption{HealthCheckInterval: time.Hour})
+}
+
+func (suite *FailoverTestSuite) TearDownTest() {
+	suite.provider.Close()
+}
+
+func Test_FailoverTestSuite(t *testing.T) {
+	suite.Run(t, new(FailoverTestSuite))
+}
@@ -30,14 +30,34 @@
 package provider
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/alanchchen/web3go/rpc"
 )
 
+// HTTPError is returned when the node answers with an HTTP error status,
+// e.g. a proxy reporting 429 or 502, even if the body holds a JSON RPC
+// response.
+type HTTPError struct {
+	StatusCode int
+	Body       string
+	// Response is the JSON RPC response in the body, if any.
+	Response rpc.Response
+	// RetryAfter is the delay asked by the Retry-After header, 0 if none.
+	RetryAfter time.Duration
+}
+
+func (err *HTTPError) Error() string {
+	return fmt.Sprintf("Unexpected HTTP status %d, %s", err.StatusCode, err.Body)
+}
+
 // HTTPProvider provides basic web3 interface
 type HTTPProvider struct {
 	host string
@@ -55,14 +75,16 @@ func NewHTTPProvider(host string, method rpc.RPC) Provider {
 	return &HTTPProvider{host: host, rpc: method}
 }
 
-// IsConnected ...
+// IsConnected tells if the node answers net_listening with true. Errors and
+// unexpected results count as not connected.
 func (provider *HTTPProvider) IsConnected() bool {
 	req := provider.rpc.NewRequest("net_listening")
 	resp, err := provider.Send(req)
-	if err != nil {
+	if err != nil || resp.Error() != nil {
 		return false
 	}
-	return resp.Get("result").(bool)
+	listening, ok := resp.Get("result").(bool)
+	return ok && listening
 }
 
 // Send JSON RPC request through http client
@@ -80,12 +102,68 @@ func (provider *HTTPProvider) Send(request rpc.Request) (response rpc.Response,
 	}
 
 	response = provider.rpc.NewResponse(body)
+	if !isSuccess(resp.StatusCode) {
+		return nil, newHTTPError(resp, body, response)
+	}
 	if response == nil {
 		err = fmt.Errorf("Malformed response body, %s", string(body))
 	}
 	return response, err
 }
 
+// SendBatch sends several JSON RPC requests in one http round trip
+func (provider *HTTPProvider) SendBatch(requests []rpc.Request) (responses []rpc.Response, err error) {
+	buffer := new(bytes.Buffer)
+	buffer.WriteString("[")
+	for i, request := range requests {
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+		buffer.WriteString(request.String())
+	}
+	buffer.WriteString("]")
+
+	contentType := provider.determineContentType()
+	resp, err := http.Post(provider.host, contentType, buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isSuccess(resp.StatusCode) {
+		// Nodes answer a rejected batch with a single response.
+		return nil, newHTTPError(resp, body, provider.rpc.NewResponse(body))
+	}
+	var rawResponses []json.RawMessage
+	if err := json.Unmarshal(body, &rawResponses); err != nil {
+		return nil, fmt.Errorf("Malformed batch response body, %s", string(body))
+	}
+
+	byID := make(map[uint64]rpc.Response, len(rawResponses))
+	for _, raw := range rawResponses {
+		response := provider.rpc.NewResponse(raw)
+		if response == nil {
+			return nil, fmt.Errorf("Malformed response body, %s", string(raw))
+		}
+		byID[response.ID()] = response
+	}
+
+	responses = make([]rpc.Response, len(requests))
+	for i, request := range requests {
+		response, ok := byID[request.ID()]
+		if !ok {
+			return nil, fmt.Errorf("Missing response for request %d", request.ID())
+		}
+		responses[i] = response
+	}
+	return responses, nil
+}
+
 func (provider *HTTPProvider) GetRPCMethod() rpc.RPC {
 	return provider.rpc
 }
@@ -98,3 +176,34 @@ func (provider *HTTPProvider) determineContentType() string {
 		return "application/json"
 	}
 }
+
+func isSuccess(statusCode int) bool {
+	return statusCode >= 200 && statusCode < 300
+}
+
+func newHTTPError(resp *http.Response, body []byte, response rpc.Response) *HTTPError {
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		Response:   response,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// parseRetryAfter decodes a Retry-After header, either seconds or an HTTP
+// date. It returns 0 if the header is missing or malformed.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
@@ -30,10 +30,14 @@
 package provider
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/alanchchen/web3go/rpc"
 	"github.com/stretchr/testify/assert"
@@ -51,6 +55,23 @@ func (suite *HTTPProviderTestSuite) Test_IsConnected() {
 	assert.EqualValues(suite.T(), true, provider.IsConnected(), "should be equal")
 }
 
+func (suite *HTTPProviderTestSuite) Test_NotListening() {
+	for _, reply := range []string{
+		`{"jsonrpc":"2.0","id":%d,"error":{"code":-32601,"message":"Method not found"}}`,
+		`{"jsonrpc":"2.0","id":%d,"result":null}`,
+		`{"jsonrpc":"2.0","id":%d,"result":"yes"}`,
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req := rpc.JSONRPCRequest{}
+			json.NewDecoder(r.Body).Decode(&req)
+			fmt.Fprintf(w, reply, req.Identifier)
+		}))
+		provider := NewHTTPProvider(server.URL, nil)
+		assert.False(suite.T(), provider.IsConnected(), "Should be false")
+		server.Close()
+	}
+}
+
 func (suite *HTTPProviderTestSuite) Test_Send() {
 	provider := suite.provider
 	req := &rpc.JSONRPCRequest{
@@ -71,25 +92,110 @@ func (suite *HTTPProviderTestSuite) Test_GetRPCMethod() {
 	assert.NotNil(suite.T(), provider.GetRPCMethod(), "should be equal")
 }
 
+func (suite *HTTPProviderTestSuite) Test_SendBatch() {
+	provider := suite.provider.(BatchProvider)
+	method := provider.GetRPCMethod()
+	requests := []rpc.Request{
+		method.NewRequest("net_listening"),
+		method.NewRequest("test_method"),
+	}
+	responses, err := provider.SendBatch(requests)
+
+	assert.NoError(suite.T(), err, "Should be no error")
+	if assert.Len(suite.T(), responses, 2, "should be equal") {
+		assert.EqualValues(suite.T(), requests[0].ID(), responses[0].ID(), "should be equal")
+		assert.EqualValues(suite.T(), true, responses[0].Get("result"), "should be equal")
+		assert.EqualValues(suite.T(), requests[1].ID(), responses[1].ID(), "should be equal")
+		assert.EqualValues(suite.T(), "ok", responses[1].Get("result"), "should be equal")
+	}
+}
+
+func (suite *HTTPProviderTestSuite) Test_HTTPError() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	provider := NewHTTPProvider(server.URL, nil)
+	_, err := provider.Send(provider.GetRPCMethod().NewRequest("test_method"))
+	if assert.IsType(suite.T(), &HTTPError{}, err, "Should be an HTTP error") {
+		assert.Equal(suite.T(), http.StatusBadGateway, err.(*HTTPError).StatusCode, "Should be equal")
+	}
+
+	_, err = provider.(BatchProvider).SendBatch([]rpc.Request{provider.GetRPCMethod().NewRequest("test_method")})
+	assert.IsType(suite.T(), &HTTPError{}, err, "Should be an HTTP error")
+}
+
+func (suite *HTTPProviderTestSuite) Test_RateLimited() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"Rate limited"}}`))
+	}))
+	defer server.Close()
+
+	provider := NewHTTPProvider(server.URL, nil)
+	_, err := provider.Send(provider.GetRPCMethod().NewRequest("test_method"))
+	if assert.IsType(suite.T(), &HTTPError{}, err, "Should be an HTTP error") {
+		httpErr := err.(*HTTPError)
+		assert.Equal(suite.T(), http.StatusTooManyRequests, httpErr.StatusCode, "Should be equal")
+		assert.Equal(suite.T(), 2*time.Second, httpErr.RetryAfter, "Should be equal")
+		if assert.NotNil(suite.T(), httpErr.Response, "Should keep the response") {
+			code, _ := rpc.ErrorCode(httpErr.Response.Error())
+			assert.Equal(suite.T(), int64(rpc.CodeLimitExceeded), code, "Should be equal")
+		}
+	}
+
+	_, err = provider.(BatchProvider).SendBatch([]rpc.Request{provider.GetRPCMethod().NewRequest("test_method")})
+	if assert.IsType(suite.T(), &HTTPError{}, err, "Should be an HTTP error") {
+		assert.NotNil(suite.T(), err.(*HTTPError).Response, "Should keep the response")
+	}
+}
+
+func (suite *HTTPProviderTestSuite) Test_ParseRetryAfter() {
+	now := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(suite.T(), time.Duration(0), parseRetryAfter("", now), "Should be equal")
+	assert.Equal(suite.T(), 5*time.Second, parseRetryAfter("5", now), "Should be equal")
+	assert.Equal(suite.T(), time.Minute, parseRetryAfter("Fri, 01 Jan 2016 00:01:00 GMT", now), "Should be equal")
+	assert.Equal(suite.T(), time.Duration(0), parseRetryAfter("Thu, 31 Dec 2015 00:00:00 GMT", now), "Should be equal")
+	assert.Equal(suite.T(), time.Duration(0), parseRetryAfter("soon", now), "Should be equal")
+}
+
 func (suite *HTTPProviderTestSuite) SetupTest() {
+	handle := func(req rpc.JSONRPCRequest) rpc.JSONRPCResponse {
+		resp := rpc.JSONRPCResponse{Version: "2.0", Identifier: req.Identifier}
+		switch req.Method {
+		case "net_listening":
+			resp.Result = true
+		default:
+			resp.Result = "ok"
+		}
+		return resp
+	}
+
 	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		decoder := json.NewDecoder(r.Body)
-		req := rpc.JSONRPCRequest{}
-		resp := rpc.JSONRPCResponse{Version: "2.0"}
-		err := decoder.Decode(&req)
-		if err != nil {
-			resp.Identifier = 0
-			resp.Result = "error"
+		body, _ := ioutil.ReadAll(r.Body)
+		var jsonBlob []byte
+		if bytes.HasPrefix(body, []byte("[")) {
+			var reqs []rpc.JSONRPCRequest
+			var resps []rpc.JSONRPCResponse
+			json.Unmarshal(body, &reqs)
+			// Reply in reverse order, as servers are free to reorder batches.
+			for i := len(reqs) - 1; i >= 0; i-- {
+				resps = append(resps, handle(reqs[i]))
+			}
+			jsonBlob, _ = json.Marshal(resps)
 		} else {
-			resp.Identifier = req.Identifier
-			switch req.Method {
-			case "net_listening":
-				resp.Result = true
-			default:
-				resp.Result = "ok"
+			req := rpc.JSONRPCRequest{}
+			resp := rpc.JSONRPCResponse{Version: "2.0"}
+			if err := json.Unmarshal(body, &req); err != nil {
+				resp.Identifier = 0
+				resp.Result = "error"
+			} else {
+				resp = handle(req)
 			}
+			jsonBlob, _ = json.Marshal(resp)
 		}
-		jsonBlob, _ := json.Marshal(resp)
 		w.Write(jsonBlob)
 	}))
 	suite.provider = NewHTTPProvider(suite.server.URL, rpc.GetDefaultMethod())
@@ -0,0 +1,310 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"bytes"
+	"expvar"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alanchchen/web3go/rpc"
+)
+
+// RequestStats describes a request sent through the Measure middleware.
+type RequestStats struct {
+	Method string
+	// Duration is the time until the response, for a batch the time of
+	// the whole batch.
+	Duration time.Duration
+	// RequestSize and ResponseSize estimate the sizes of the messages in
+	// bytes, from their JSON encoding rather than the bytes on the wire.
+	// They are only set for a SizeMetrics, ResponseSize is 0 if the request
+	// failed without response.
+	RequestSize  int
+	ResponseSize int
+	// Err is the error of the request, or the error of its response.
+	Err error
+}
+
+// Metrics receives the statistics of every request.
+type Metrics interface {
+	Observe(stats *RequestStats)
+}
+
+// SizeMetrics is a Metrics using the message sizes of RequestStats. Messages
+// are encoded again to be measured, which is only done for a SizeMetrics.
+type SizeMetrics interface {
+	Metrics
+	MeasureSizes() bool
+}
+
+// Measure returns a middleware reporting every request to metrics,
+// including each request of a batch.
+func Measure(metrics Metrics) Middleware {
+	sizes := false
+	if m, ok := metrics.(SizeMetrics); ok {
+		sizes = m.MeasureSizes()
+	}
+	send := func(request rpc.Request, next SendFunc) (rpc.Response, error) {
+		start := time.Now()
+		response, err := next(request)
+		metrics.Observe(newRequestStats(request, response, err, time.Since(start), sizes))
+		return response, err
+	}
+	batch := func(requests []rpc.Request, next BatchSendFunc) ([]rpc.Response, error) {
+		start := time.Now()
+		responses, err := next(requests)
+		duration := time.Since(start)
+		for i, request := range requests {
+			var response rpc.Response
+			if err == nil {
+				response = responses[i]
+			}
+			metrics.Observe(newRequestStats(request, response, err, duration, sizes))
+		}
+		return responses, err
+	}
+	return Intercept(send, batch)
+}
+
+func newRequestStats(request rpc.Request, response rpc.Response, err error, duration time.Duration, sizes bool) *RequestStats {
+	stats := &RequestStats{
+		Duration: duration,
+		Err:      responseError(response, err),
+	}
+	stats.Method, _ = request.Get("method").(string)
+	if sizes {
+		stats.RequestSize = len(request.String())
+		if response != nil {
+			stats.ResponseSize = len(response.String())
+		}
+	}
+	return stats
+}
+
+// latencyBuckets are the upper bounds of the latency histograms.
+var latencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// latencyHistogram is an expvar.Var counting durations per bucket. Buckets
+// are not cumulative, the last one counts the durations above all bounds.
+type latencyHistogram struct {
+	lock   sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    time.Duration
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{counts: make([]uint64, len(latencyBuckets)+1)}
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	i := 0
+	for i < len(latencyBuckets) && d > latencyBuckets[i] {
+		i++
+	}
+	h.counts[i]++
+	h.count++
+	h.sum += d
+}
+
+// String returns the histogram as JSON, with the sum in milliseconds.
+func (h *latencyHistogram) String() string {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	buffer := new(bytes.Buffer)
+	buffer.WriteString(`{"buckets":{`)
+	for i, count := range h.counts {
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+		bound := "+Inf"
+		if i < len(latencyBuckets) {
+			bound = latencyBuckets[i].String()
+		}
+		fmt.Fprintf(buffer, "%q:%d", bound, count)
+	}
+	fmt.Fprintf(buffer, `},"count":%d,"sum_ms":%s}`, h.count, strconv.FormatFloat(h.sum.Seconds()*1000, 'f', -1, 64))
+	return buffer.String()
+}
+
+// ExpvarMetrics publishes request statistics with expvar, in a map holding:
+//
+//	requests        requests by method
+//	errors          failed requests by method
+//	error_codes     failed requests by JSON RPC error code, "none" for
+//	                errors without code
+//	latency         latency histograms by method
+//	request_bytes   estimated request bytes by method
+//	response_bytes  estimated response bytes by method
+type ExpvarMetrics struct {
+	requests      *expvar.Map
+	errors        *expvar.Map
+	errorCodes    *expvar.Map
+	latency       *expvar.Map
+	requestBytes  *expvar.Map
+	responseBytes *expvar.Map
+
+	lock       sync.Mutex
+	histograms map[string]*latencyHistogram
+}
+
+// NewExpvarMetrics publishes the statistics under name. Like expvar.NewMap,
+// it panics if name is already used.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	m := &ExpvarMetrics{
+		requests:      new(expvar.Map).Init(),
+		errors:        new(expvar.Map).Init(),
+		errorCodes:    new(expvar.Map).Init(),
+		latency:       new(expvar.Map).Init(),
+		requestBytes:  new(expvar.Map).Init(),
+		responseBytes: new(expvar.Map).Init(),
+		histograms:    make(map[string]*latencyHistogram),
+	}
+	root := expvar.NewMap(name)
+	root.Set("requests", m.requests)
+	root.Set("errors", m.errors)
+	root.Set("error_codes", m.errorCodes)
+	root.Set("latency", m.latency)
+	root.Set("request_bytes", m.requestBytes)
+	root.Set("response_bytes", m.responseBytes)
+	return m
+}
+
+// MeasureSizes returns true, ExpvarMetrics publishes the message sizes.
+func (m *ExpvarMetrics) MeasureSizes() bool {
+	return true
+}
+
+// Observe records stats.
+func (m *ExpvarMetrics) Observe(stats *RequestStats) {
+	m.requests.Add(stats.Method, 1)
+	m.requestBytes.Add(stats.Method, int64(stats.RequestSize))
+	m.responseBytes.Add(stats.Method, int64(stats.ResponseSize))
+	if stats.Err != nil {
+		m.errors.Add(stats.Method, 1)
+		code := "none"
+		if c, ok := rpc.ErrorCode(stats.Err); ok {
+			code = strconv.FormatInt(c, 10)
+		}
+		m.errorCodes.Add(code, 1)
+	}
+
+	m.lock.Lock()
+	h, ok := m.histograms[stats.Method]
+	if !ok {
+		h = newLatencyHistogram()
+		m.histograms[stats.Method] = h
+		m.latency.Set(stats.Method, h)
+	}
+	m.lock.Unlock()
+	h.observe(stats.Duration)
+}
+
+// Span describes a request traced by the Trace middleware.
+type Span struct {
+	Method string
+	ID     uint64
+	Start  time.Time
+	// Duration and Err are set when the request ends. Err is the error of
+	// the request, or the error of its response.
+	Duration time.Duration
+	Err      error
+	// Data is free for the hooks, e.g. to hold the span of a tracing
+	// library between OnStart and OnEnd.
+	Data interface{}
+}
+
+// TraceHooks are called around every request. Either can be nil.
+type TraceHooks struct {
+	OnStart func(span *Span)
+	OnEnd   func(span *Span)
+}
+
+// Trace returns a middleware calling hooks around every request, including
+// each request of a batch.
+func Trace(hooks TraceHooks) Middleware {
+	start := func(request rpc.Request) *Span {
+		span := &Span{ID: request.ID(), Start: time.Now()}
+		span.Method, _ = request.Get("method").(string)
+		if hooks.OnStart != nil {
+			hooks.OnStart(span)
+		}
+		return span
+	}
+	end := func(span *Span, response rpc.Response, err error) {
+		span.Duration = time.Since(span.Start)
+		span.Err = responseError(response, err)
+		if hooks.OnEnd != nil {
+			hooks.OnEnd(span)
+		}
+	}
+
+	send := func(request rpc.Request, next SendFunc) (rpc.Response, error) {
+		span := start(request)
+		response, err := next(request)
+		end(span, response, err)
+		return response, err
+	}
+	batch := func(requests []rpc.Request, next BatchSendFunc) ([]rpc.Response, error) {
+		spans := make([]*Span, len(requests))
+		for i, request := range requests {
+			spans[i] = start(request)
+		}
+		responses, err := next(requests)
+		for i, span := range spans {
+			var response rpc.Response
+			if err == nil {
+				response = responses[i]
+			}
+			end(span, response, err)
+		}
+		return responses, err
+	}
+	return Intercept(send, batch)
+}
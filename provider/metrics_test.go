@@ -0,0 +1,230 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alanchchen/web3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// statsRecorder is a Metrics keeping all stats.
+type statsRecorder struct {
+	lock  sync.Mutex
+	stats []*RequestStats
+	sizes bool
+}
+
+func (r *statsRecorder) MeasureSizes() bool {
+	return r.sizes
+}
+
+func (r *statsRecorder) Observe(stats *RequestStats) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.stats = append(r.stats, stats)
+}
+
+// expvarRuns numbers the published names, expvar names can't be reused
+// when the suite runs several times.
+var expvarRuns int32
+
+type MetricsTestSuite struct {
+	suite.Suite
+	stub *stubBatchProvider
+}
+
+func (suite *MetricsTestSuite) Test_Measure() {
+	metrics := &statsRecorder{sizes: true}
+	p := Chain(suite.stub, Measure(metrics))
+	request := p.GetRPCMethod().NewRequest("eth_blockNumber")
+	response, err := p.Send(request)
+	assert.NoError(suite.T(), err, "Should be no error")
+
+	if assert.Len(suite.T(), metrics.stats, 1, "Should be equal") {
+		stats := metrics.stats[0]
+		assert.Equal(suite.T(), "eth_blockNumber", stats.Method, "Should be equal")
+		assert.Equal(suite.T(), len(request.String()), stats.RequestSize, "Should be equal")
+		assert.Equal(suite.T(), len(response.String()), stats.ResponseSize, "Should be equal")
+		assert.NoError(suite.T(), stats.Err, "Should be no error")
+		assert.True(suite.T(), stats.Duration > 0, "Should be measured")
+	}
+
+	_, err = p.Send(p.GetRPCMethod().NewRequest("eth_call"))
+	assert.NoError(suite.T(), err, "Should be no error")
+	code, _ := rpc.ErrorCode(metrics.stats[1].Err)
+	assert.Equal(suite.T(), int64(rpc.CodeInternalError), code, "Should be equal")
+
+	_, err = p.Send(p.GetRPCMethod().NewRequest("eth_sendRawTransaction"))
+	assert.Error(suite.T(), err, "Should be error")
+	assert.Equal(suite.T(), err, metrics.stats[2].Err, "Should be equal")
+	assert.Equal(suite.T(), 0, metrics.stats[2].ResponseSize, "Should be equal")
+}
+
+func (suite *MetricsTestSuite) Test_WithoutSizes() {
+	metrics := &statsRecorder{}
+	p := Chain(suite.stub, Measure(metrics))
+	_, err := p.Send(p.GetRPCMethod().NewRequest("eth_blockNumber"))
+	assert.NoError(suite.T(), err, "Should be no error")
+	if assert.Len(suite.T(), metrics.stats, 1, "Should be equal") {
+		assert.Equal(suite.T(), 0, metrics.stats[0].RequestSize, "Should be equal")
+		assert.Equal(suite.T(), 0, metrics.stats[0].ResponseSize, "Should be equal")
+	}
+}
+
+func (suite *MetricsTestSuite) Test_MeasureBatch() {
+	metrics := &statsRecorder{}
+	p := Chain(suite.stub, Measure(metrics)).(BatchProvider)
+	method := p.GetRPCMethod()
+	_, err := p.SendBatch([]rpc.Request{method.NewRequest("eth_blockNumber"), method.NewRequest("eth_call")})
+	assert.NoError(suite.T(), err, "Should be no error")
+
+	if assert.Len(suite.T(), metrics.stats, 2, "Should be equal") {
+		assert.Equal(suite.T(), "eth_blockNumber", metrics.stats[0].Method, "Should be equal")
+		assert.NoError(suite.T(), metrics.stats[0].Err, "Should be no error")
+		assert.Equal(suite.T(), "eth_call", metrics.stats[1].Method, "Should be equal")
+		assert.Error(suite.T(), metrics.stats[1].Err, "Should be error")
+	}
+}
+
+func (suite *MetricsTestSuite) Test_ExpvarMetrics() {
+	name := fmt.Sprintf("web3_test_rpc_%d", atomic.AddInt32(&expvarRuns, 1))
+	metrics := NewExpvarMetrics(name)
+	p := Chain(suite.stub, Measure(metrics))
+	for _, method := range []string{"eth_blockNumber", "eth_blockNumber", "eth_call", "eth_sendRawTransaction"} {
+		p.Send(p.GetRPCMethod().NewRequest(method))
+	}
+
+	var published struct {
+		Requests      map[string]int64 `json:"requests"`
+		Errors        map[string]int64 `json:"errors"`
+		ErrorCodes    map[string]int64 `json:"error_codes"`
+		RequestBytes  map[string]int64 `json:"request_bytes"`
+		ResponseBytes map[string]int64 `json:"response_bytes"`
+		Latency       map[string]struct {
+			Buckets map[string]uint64 `json:"buckets"`
+			Count   uint64            `json:"count"`
+		} `json:"latency"`
+	}
+	err := json.Unmarshal([]byte(expvar.Get(name).String()), &published)
+	assert.NoError(suite.T(), err, "Should be no error")
+
+	assert.Equal(suite.T(), int64(2), published.Requests["eth_blockNumber"], "Should be equal")
+	assert.Equal(suite.T(), int64(1), published.Requests["eth_call"], "Should be equal")
+	assert.Equal(suite.T(), map[string]int64{"eth_call": 1, "eth_sendRawTransaction": 1}, published.Errors, "Should be equal")
+	assert.Equal(suite.T(), map[string]int64{"-32603": 1, "none": 1}, published.ErrorCodes, "Should be equal")
+	assert.True(suite.T(), published.RequestBytes["eth_blockNumber"] > 0, "Should count bytes")
+	assert.True(suite.T(), published.ResponseBytes["eth_blockNumber"] > 0, "Should count bytes")
+	assert.Equal(suite.T(), int64(0), published.ResponseBytes["eth_sendRawTransaction"], "Should be equal")
+	assert.Equal(suite.T(), uint64(2), published.Latency["eth_blockNumber"].Count, "Should be equal")
+	assert.Equal(suite.T(), uint64(2), published.Latency["eth_blockNumber"].Buckets["5ms"], "Should be equal")
+}
+
+func (suite *MetricsTestSuite) Test_LatencyHistogram() {
+	h := newLatencyHistogram()
+	h.observe(time.Millisecond)
+	h.observe(30 * time.Millisecond)
+	h.observe(time.Minute)
+
+	var published struct {
+		Buckets map[string]uint64 `json:"buckets"`
+		Count   uint64            `json:"count"`
+		Sum     float64           `json:"sum_ms"`
+	}
+	assert.NoError(suite.T(), json.Unmarshal([]byte(h.String()), &published), "Should be no error")
+	assert.Equal(suite.T(), uint64(1), published.Buckets["5ms"], "Should be equal")
+	assert.Equal(suite.T(), uint64(1), published.Buckets["50ms"], "Should be equal")
+	assert.Equal(suite.T(), uint64(1), published.Buckets["+Inf"], "Should be equal")
+	assert.Equal(suite.T(), uint64(0), published.Buckets["25ms"], "Should be equal")
+	assert.Equal(suite.T(), uint64(3), published.Count, "Should be equal")
+	assert.Equal(suite.T(), 60031.0, published.Sum, "Should be equal")
+}
+
+func (suite *MetricsTestSuite) Test_Trace() {
+	var started, ended []*Span
+	p := Chain(suite.stub, Trace(TraceHooks{
+		OnStart: func(span *Span) {
+			span.Data = "started"
+			started = append(started, span)
+		},
+		OnEnd: func(span *Span) {
+			ended = append(ended, span)
+		},
+	}))
+
+	request := p.GetRPCMethod().NewRequest("eth_call")
+	p.Send(request)
+	if assert.Len(suite.T(), ended, 1, "Should be equal") {
+		assert.Equal(suite.T(), started[0], ended[0], "Should be the same span")
+		assert.Equal(suite.T(), "eth_call", ended[0].Method, "Should be equal")
+		assert.Equal(suite.T(), request.ID(), ended[0].ID, "Should be equal")
+		assert.Equal(suite.T(), "started", ended[0].Data, "Should be equal")
+		assert.EqualError(suite.T(), ended[0].Err, "Boom", "Should be equal")
+	}
+
+	method := p.GetRPCMethod()
+	batch := []rpc.Request{method.NewRequest("eth_blockNumber"), method.NewRequest("eth_gasPrice")}
+	p.(BatchProvider).SendBatch(batch)
+	if assert.Len(suite.T(), ended, 3, "Should be equal") {
+		assert.Equal(suite.T(), batch[0].ID(), ended[1].ID, "Should be equal")
+		assert.Equal(suite.T(), batch[1].ID(), ended[2].ID, "Should be equal")
+		assert.NoError(suite.T(), ended[2].Err, "Should be no error")
+	}
+
+	// Hooks are optional.
+	p = Chain(suite.stub, Trace(TraceHooks{}))
+	_, err := p.Send(p.GetRPCMethod().NewRequest("eth_blockNumber"))
+	assert.NoError(suite.T(), err, "Should be no error")
+}
+
+func (suite *MetricsTestSuite) SetupTest() {
+	suite.stub = &stubBatchProvider{stubProvider: newStubProvider(func(request rpc.Request) (interface{}, error) {
+		switch request.Get("method").(string) {
+		case "eth_call":
+			return nil, &rpc.JSONRPCError{Code: rpc.CodeInternalError, Message: "Boom"}
+		case "eth_sendRawTransaction":
+			return nil, errors.New("Connection refused")
+		}
+		return "0x10", nil
+	})}
+}
+
+func Test_MetricsTestSuite(t *testing.T) {
+	suite.Run(t, new(MetricsTestSuite))
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"log"
+	"time"
+
+	"github.com/alanchchen/web3go/rpc"
+)
+
+// Middleware wraps a provider to add behavior around its requests, such as
+// logging, retries or metrics.
+type Middleware func(next Provider) Provider
+
+// Chain stacks middlewares around provider. The first middleware is the
+// outermost one, it sees requests first and responses last.
+func Chain(provider Provider, middlewares ...Middleware) Provider {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		provider = middlewares[i](provider)
+	}
+	return provider
+}
+
+// SendFunc sends a request.
+type SendFunc func(rpc.Request) (rpc.Response, error)
+
+// BatchSendFunc sends requests in a single round trip.
+type BatchSendFunc func([]rpc.Request) ([]rpc.Response, error)
+
+// Interceptor handles a request, usually by calling next.
+type Interceptor func(request rpc.Request, next SendFunc) (rpc.Response, error)
+
+// BatchInterceptor handles a batch of requests, usually by calling next.
+type BatchInterceptor func(requests []rpc.Request, next BatchSendFunc) ([]rpc.Response, error)
+
+// Intercept returns a middleware calling send around each request and batch
+// around each batch. If batch is nil, batches are sent as they are. The
+// wrapped provider is a BatchProvider only if the next one is.
+func Intercept(send Interceptor, batch BatchInterceptor) Middleware {
+	return func(next Provider) Provider {
+		p := &interceptedProvider{next: next, send: send}
+		if batchProvider, ok := next.(BatchProvider); ok {
+			return &interceptedBatchProvider{interceptedProvider: p, nextBatch: batchProvider, batch: batch}
+		}
+		return p
+	}
+}
+
+type interceptedProvider struct {
+	next Provider
+	send Interceptor
+}
+
+// IsConnected asks the next provider.
+func (provider *interceptedProvider) IsConnected() bool {
+	return provider.next.IsConnected()
+}
+
+// Send passes request through the interceptor.
+func (provider *interceptedProvider) Send(request rpc.Request) (rpc.Response, error) {
+	if provider.send == nil {
+		return provider.next.Send(request)
+	}
+	return provider.send(request, provider.next.Send)
+}
+
+func (provider *interceptedProvider) GetRPCMethod() rpc.RPC {
+	return provider.next.GetRPCMethod()
+}
+
+type interceptedBatchProvider struct {
+	*interceptedProvider
+	nextBatch BatchProvider
+	batch     BatchInterceptor
+}
+
+// SendBatch passes requests through the batch interceptor.
+func (provider *interceptedBatchProvider) SendBatch(requests []rpc.Request) ([]rpc.Response, error) {
+	if provider.batch == nil {
+		return provider.nextBatch.SendBatch(requests)
+	}
+	return provider.batch(requests, provider.nextBatch.SendBatch)
+}
+
+// Logging returns a middleware writing the method, duration and error of
+// every request to logger, or to the standard logger if nil.
+func Logging(logger *log.Logger) Middleware {
+	logf := log.Printf
+	if logger != nil {
+		logf = logger.Printf
+	}
+
+	send := func(request rpc.Request, next SendFunc) (rpc.Response, error) {
+		start := time.Now()
+		response, err := next(request)
+		if failure := responseError(response, err); failure != nil {
+			logf("%v #%d failed after %v: %v", request.Get("method"), request.ID(), time.Since(start), failure)
+		} else {
+			logf("%v #%d done in %v", request.Get("method"), request.ID(), time.Since(start))
+		}
+		return response, err
+	}
+	batch := func(requests []rpc.Request, next BatchSendFunc) ([]rpc.Response, error) {
+		start := time.Now()
+		responses, err := next(requests)
+		if err != nil {
+			logf("batch of %d failed after %v: %v", len(requests), time.Since(start), err)
+		} else {
+			logf("batch of %d done in %v", len(requests), time.Since(start))
+		}
+		return responses, err
+	}
+	return Intercept(send, batch)
+}
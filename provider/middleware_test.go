@@ -0,0 +1,196 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alanchchen/web3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// stubProvider answers requests with handle and counts them by method. An
+// *rpc.JSONRPCError returned by handle is sent as an error response, other
+// errors fail the request.
+type stubProvider struct {
+	rpc       rpc.RPC
+	handle    func(request rpc.Request) (interface{}, error)
+	connected bool
+
+	lock  sync.Mutex
+	calls map[string]int
+}
+
+func newStubProvider(handle func(request rpc.Request) (interface{}, error)) *stubProvider {
+	return &stubProvider{
+		rpc:       rpc.GetDefaultMethod(),
+		handle:    handle,
+		connected: true,
+		calls:     make(map[string]int),
+	}
+}
+
+func (p *stubProvider) IsConnected() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.connected
+}
+
+func (p *stubProvider) Send(request rpc.Request) (rpc.Response, error) {
+	p.lock.Lock()
+	p.calls[request.Get("method").(string)]++
+	p.lock.Unlock()
+
+	result, err := p.handle(request)
+	response := &rpc.JSONRPCResponse{Version: "2.0", Identifier: request.ID(), Result: result}
+	if rpcErr, ok := err.(*rpc.JSONRPCError); ok {
+		response.Err = rpcErr
+	} else if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (p *stubProvider) GetRPCMethod() rpc.RPC {
+	return p.rpc
+}
+
+func (p *stubProvider) count(method string) int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.calls[method]
+}
+
+// stubBatchProvider is a stubProvider able to send batches.
+type stubBatchProvider struct {
+	*stubProvider
+	batches int
+}
+
+func (p *stubBatchProvider) SendBatch(requests []rpc.Request) ([]rpc.Response, error) {
+	p.lock.Lock()
+	p.batches++
+	p.lock.Unlock()
+
+	responses := make([]rpc.Response, len(requests))
+	for i, request := range requests {
+		response, err := p.Send(request)
+		if err != nil {
+			return nil, err
+		}
+		responses[i] = response
+	}
+	return responses, nil
+}
+
+func echo(request rpc.Request) (interface{}, error) {
+	return request.Get("method"), nil
+}
+
+type MiddlewareTestSuite struct {
+	suite.Suite
+}
+
+func (suite *MiddlewareTestSuite) Test_Chain() {
+	var order []string
+	trace := func(name string) Middleware {
+		return Intercept(func(request rpc.Request, next SendFunc) (rpc.Response, error) {
+			order = append(order, name+" in")
+			response, err := next(request)
+			order = append(order, name+" out")
+			return response, err
+		}, nil)
+	}
+
+	p := Chain(newStubProvider(echo), trace("a"), trace("b"))
+	response, err := p.Send(p.GetRPCMethod().NewRequest("eth_blockNumber"))
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), "eth_blockNumber", response.Get("result"), "Should be equal")
+	assert.Equal(suite.T(), []string{"a in", "b in", "b out", "a out"}, order, "Should be equal")
+	assert.True(suite.T(), p.IsConnected(), "Should be true")
+}
+
+func (suite *MiddlewareTestSuite) Test_ShortCircuit() {
+	stub := newStubProvider(echo)
+	failure := errors.New("Refused")
+	p := Chain(stub, Intercept(func(request rpc.Request, next SendFunc) (rpc.Response, error) {
+		return nil, failure
+	}, nil))
+
+	_, err := p.Send(p.GetRPCMethod().NewRequest("eth_blockNumber"))
+	assert.Equal(suite.T(), failure, err, "Should be equal")
+	assert.Equal(suite.T(), 0, stub.count("eth_blockNumber"), "Should be equal")
+}
+
+func (suite *MiddlewareTestSuite) Test_Batch() {
+	stub := newStubProvider(echo)
+	_, ok := Chain(stub, Intercept(nil, nil)).(BatchProvider)
+	assert.False(suite.T(), ok, "Should not be a batch provider")
+
+	batches := 0
+	batchStub := &stubBatchProvider{stubProvider: stub}
+	p := Chain(batchStub, Intercept(nil, func(requests []rpc.Request, next BatchSendFunc) ([]rpc.Response, error) {
+		batches++
+		return next(requests)
+	}))
+	batchProvider, ok := p.(BatchProvider)
+	if assert.True(suite.T(), ok, "Should be a batch provider") {
+		method := p.GetRPCMethod()
+		responses, err := batchProvider.SendBatch([]rpc.Request{method.NewRequest("eth_a"), method.NewRequest("eth_b")})
+		assert.NoError(suite.T(), err, "Should be no error")
+		assert.Len(suite.T(), responses, 2, "Should be equal")
+		assert.Equal(suite.T(), 1, batches, "Should be equal")
+		assert.Equal(suite.T(), 1, batchStub.batches, "Should be equal")
+	}
+}
+
+func (suite *MiddlewareTestSuite) Test_Logging() {
+	buffer := new(bytes.Buffer)
+	stub := newStubProvider(func(request rpc.Request) (interface{}, error) {
+		return nil, &rpc.JSONRPCError{Code: rpc.CodeInternalError, Message: "Boom"}
+	})
+	p := Chain(stub, Logging(log.New(buffer, "", 0)))
+
+	response, err := p.Send(p.GetRPCMethod().NewRequest("eth_call"))
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Error(suite.T(), response.Error(), "Should be error")
+	assert.True(suite.T(), strings.HasPrefix(buffer.String(), "eth_call #"), "Should log the method")
+	assert.Contains(suite.T(), buffer.String(), "Boom", "Should log the error")
+}
+
+func Test_MiddlewareTestSuite(t *testing.T) {
+	suite.Run(t, new(MiddlewareTestSuite))
+}
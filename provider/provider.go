@@ -39,3 +39,10 @@ type Provider interface {
 	Send(rpc.Request) (rpc.Response, error)
 	GetRPCMethod() rpc.RPC
 }
+
+// BatchProvider is implemented by providers that can send several requests in
+// a single round trip. Responses are returned in the order of the requests.
+type BatchProvider interface {
+	Provider
+	SendBatch([]rpc.Request) ([]rpc.Response, error)
+}
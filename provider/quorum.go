@@ -0,0 +1,281 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/alanchchen/web3go/rpc"
+)
+
+// DefaultQuorumMethods are the methods cross-checked by default.
+var DefaultQuorumMethods = []string{
+	"eth_getBalance",
+	"eth_getCode",
+	"eth_getStorageAt",
+	"eth_getTransactionCount",
+	"eth_getTransactionByHash",
+	"eth_getTransactionReceipt",
+	"eth_call",
+}
+
+// QuorumOption configures a QuorumProvider. Zero fields take defaults.
+type QuorumOption struct {
+	// Quorum is the number of providers that have to agree, a majority by
+	// default.
+	Quorum int
+	// Methods are the methods cross-checked, DefaultQuorumMethods if nil.
+	// Other requests are sent to the first provider.
+	Methods []string
+	// OnDisagreement is called when the providers don't all agree on a
+	// request, even if the quorum was reached, once all of them answered.
+	// Responses and errors are indexed like the providers.
+	OnDisagreement func(request rpc.Request, responses []rpc.Response, errs []error)
+}
+
+// QuorumError is returned when not enough providers agree on a response.
+type QuorumError struct {
+	Method string
+	Quorum int
+	// Agreed is the size of the largest group of matching responses.
+	Agreed int
+	// Errors are the errors of the providers that failed.
+	Errors []error
+}
+
+func (err *QuorumError) Error() string {
+	return fmt.Sprintf("No quorum for %s, %d of %d required providers agreed", err.Method, err.Agreed, err.Quorum)
+}
+
+// QuorumProvider sends requests to several providers and returns a response
+// once Quorum of them sent the same one. Results are compared as normalized
+// JSON, so key order and the case of hex strings don't matter. Errors are
+// compared by code.
+type QuorumProvider struct {
+	providers []Provider
+	option    QuorumOption
+	methods   map[string]bool
+}
+
+// NewQuorumProvider creates a provider cross-checking providers.
+func NewQuorumProvider(providers []Provider, option *QuorumOption) *QuorumProvider {
+	q := &QuorumProvider{providers: providers, methods: make(map[string]bool)}
+	if option != nil {
+		q.option = *option
+	}
+	if q.option.Quorum <= 0 {
+		q.option.Quorum = len(providers)/2 + 1
+	}
+	if q.option.Methods == nil {
+		q.option.Methods = DefaultQuorumMethods
+	}
+	for _, method := range q.option.Methods {
+		q.methods[method] = true
+	}
+	return q
+}
+
+// IsConnected tells if enough providers are connected to reach the quorum.
+func (q *QuorumProvider) IsConnected() bool {
+	connected := 0
+	for _, p := range q.providers {
+		if p.IsConnected() {
+			connected++
+		}
+	}
+	return connected >= q.option.Quorum
+}
+
+// GetRPCMethod returns the RPC method of the first provider.
+func (q *QuorumProvider) GetRPCMethod() rpc.RPC {
+	if len(q.providers) == 0 {
+		return rpc.GetDefaultMethod()
+	}
+	return q.providers[0].GetRPCMethod()
+}
+
+type vote struct {
+	index    int
+	response rpc.Response
+	err      error
+}
+
+// Send cross-checks request if its method requires a quorum, otherwise it
+// sends request to the first provider.
+func (q *QuorumProvider) Send(request rpc.Request) (rpc.Response, error) {
+	if len(q.providers) == 0 {
+		return nil, ErrNoProviders
+	}
+	method, _ := request.Get("method").(string)
+	if !q.methods[method] {
+		return q.providers[0].Send(request)
+	}
+
+	votes := make(chan vote, len(q.providers))
+	for i, p := range q.providers {
+		go func(i int, p Provider) {
+			response, err := p.Send(request)
+			votes <- vote{index: i, response: response, err: err}
+		}(i, p)
+	}
+
+	n := len(q.providers)
+	responses := make([]rpc.Response, n)
+	errs := make([]error, n)
+	groups := make(map[string]int)
+	best, received := 0, 0
+	var winner rpc.Response
+	for received < n {
+		v := <-votes
+		received++
+		responses[v.index], errs[v.index] = v.response, v.err
+		if v.err == nil {
+			key := normalizeResponse(v.response)
+			groups[key]++
+			if groups[key] > best {
+				best = groups[key]
+			}
+			if best >= q.option.Quorum {
+				winner = v.response
+				break
+			}
+		}
+		if best+n-received < q.option.Quorum {
+			break
+		}
+	}
+
+	var result error
+	if winner == nil {
+		quorumErr := &QuorumError{Method: method, Quorum: q.option.Quorum, Agreed: best}
+		for _, err := range errs {
+			if err != nil {
+				quorumErr.Errors = append(quorumErr.Errors, err)
+			}
+		}
+		result = quorumErr
+	}
+
+	// The remaining providers are awaited in the background to report
+	// disagreements.
+	go func() {
+		for ; received < n; received++ {
+			v := <-votes
+			responses[v.index], errs[v.index] = v.response, v.err
+		}
+		q.report(request, responses, errs)
+	}()
+
+	if result != nil {
+		return nil, result
+	}
+	return winner, nil
+}
+
+// report calls OnDisagreement unless all providers sent the same response.
+func (q *QuorumProvider) report(request rpc.Request, responses []rpc.Response, errs []error) {
+	if q.option.OnDisagreement == nil {
+		return
+	}
+	for i := range responses {
+		if errs[i] != nil || normalizeResponse(responses[i]) != normalizeResponse(responses[0]) {
+			q.option.OnDisagreement(request, responses, errs)
+			return
+		}
+	}
+}
+
+// SendBatch cross-checks batches holding requests that require a quorum one
+// request at a time. Other batches are sent to the first provider.
+func (q *QuorumProvider) SendBatch(requests []rpc.Request) ([]rpc.Response, error) {
+	if len(q.providers) == 0 {
+		return nil, ErrNoProviders
+	}
+	cross := false
+	for _, request := range requests {
+		method, _ := request.Get("method").(string)
+		cross = cross || q.methods[method]
+	}
+	if batchProvider, ok := q.providers[0].(BatchProvider); ok && !cross {
+		return batchProvider.SendBatch(requests)
+	}
+
+	responses := make([]rpc.Response, len(requests))
+	for i, request := range requests {
+		response, err := q.Send(request)
+		if err != nil {
+			return nil, err
+		}
+		responses[i] = response
+	}
+	return responses, nil
+}
+
+// normalizeResponse returns a key equal for matching responses.
+func normalizeResponse(response rpc.Response) string {
+	if err := response.Error(); err != nil {
+		if code, ok := rpc.ErrorCode(err); ok {
+			return fmt.Sprintf("error %d", code)
+		}
+		return "error " + err.Error()
+	}
+
+	data, err := json.Marshal(normalizeJSON(response.Get("result")))
+	if err != nil {
+		return fmt.Sprintf("result %v", response.Get("result"))
+	}
+	return "result " + string(data)
+}
+
+// normalizeJSON lowercases hex strings in a decoded JSON value. Maps are
+// marshaled with sorted keys.
+func normalizeJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if strings.HasPrefix(v, "0x") || strings.HasPrefix(v, "0X") {
+			return strings.ToLower(v)
+		}
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i := range v {
+			result[i] = normalizeJSON(v[i])
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key := range v {
+			result[key] = normalizeJSON(v[key])
+		}
+		return result
+	}
+	return value
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alanchchen/web3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// answering returns a provider answering every request with result.
+func answering(result interface{}, err error) *stubProvider {
+	return newStubProvider(func(request rpc.Request) (interface{}, error) {
+		return result, err
+	})
+}
+
+type QuorumTestSuite struct {
+	suite.Suite
+	disagreements chan []error
+}
+
+func (suite *QuorumTestSuite) quorum(providers ...Provider) *QuorumProvider {
+	return NewQuorumProvider(providers, &QuorumOption{
+		OnDisagreement: func(request rpc.Request, responses []rpc.Response, errs []error) {
+			suite.disagreements <- errs
+		},
+	})
+}
+
+func (suite *QuorumTestSuite) send(q *QuorumProvider, method string) (rpc.Response, error) {
+	return q.Send(q.GetRPCMethod().NewRequest(method))
+}
+
+func (suite *QuorumTestSuite) Test_Agree() {
+	receipt := map[string]interface{}{"status": "0x1", "blockHash": "0xABCD"}
+	q := suite.quorum(
+		answering(receipt, nil),
+		answering(map[string]interface{}{"blockHash": "0xabcd", "status": "0x1"}, nil),
+		answering(receipt, nil),
+	)
+
+	response, err := suite.send(q, "eth_getTransactionReceipt")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), "0x1", response.Get("result").(map[string]interface{})["status"], "Should be equal")
+
+	select {
+	case <-suite.disagreements:
+		suite.T().Error("Should not report a disagreement")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func (suite *QuorumTestSuite) Test_Majority() {
+	slow := answering("0x2", nil)
+	slow.handle = func(request rpc.Request) (interface{}, error) {
+		time.Sleep(50 * time.Millisecond)
+		return "0x2", nil
+	}
+	q := suite.quorum(answering("0x1", nil), slow, answering("0x1", nil))
+
+	start := time.Now()
+	response, err := suite.send(q, "eth_getBalance")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), "0x1", response.Get("result"), "Should be equal")
+	assert.True(suite.T(), time.Since(start) < 50*time.Millisecond, "Should not wait for the slow provider")
+
+	select {
+	case errs := <-suite.disagreements:
+		assert.Equal(suite.T(), []error{nil, nil, nil}, errs, "Should be equal")
+	case <-time.After(time.Second):
+		suite.T().Error("Should report a disagreement")
+	}
+}
+
+func (suite *QuorumTestSuite) Test_NoQuorum() {
+	failure := errors.New("Connection refused")
+	q := suite.quorum(answering("0x1", nil), answering("0x2", nil), answering(nil, failure))
+
+	_, err := suite.send(q, "eth_getBalance")
+	if assert.IsType(suite.T(), &QuorumError{}, err, "Should be a quorum error") {
+		quorumErr := err.(*QuorumError)
+		assert.Equal(suite.T(), "eth_getBalance", quorumErr.Method, "Should be equal")
+		assert.Equal(suite.T(), 2, quorumErr.Quorum, "Should be equal")
+		assert.Equal(suite.T(), 1, quorumErr.Agreed, "Should be equal")
+		assert.Equal(suite.T(), []error{failure}, quorumErr.Errors, "Should be equal")
+	}
+	<-suite.disagreements
+}
+
+func (suite *QuorumTestSuite) Test_ErrorsByCode() {
+	q := suite.quorum(
+		answering(nil, &rpc.JSONRPCError{Code: rpc.CodeInvalidParams, Message: "invalid argument 0"}),
+		answering(nil, &rpc.JSONRPCError{Code: rpc.CodeInvalidParams, Message: "Invalid params"}),
+	)
+
+	response, err := suite.send(q, "eth_getBalance")
+	assert.NoError(suite.T(), err, "Should be no error")
+	code, _ := rpc.ErrorCode(response.Error())
+	assert.Equal(suite.T(), int64(rpc.CodeInvalidParams), code, "Should be equal")
+}
+
+func (suite *QuorumTestSuite) Test_OtherMethods() {
+	first, second := answering("0x1", nil), answering("0x2", nil)
+	q := suite.quorum(first, second)
+
+	response, err := suite.send(q, "eth_blockNumber")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), "0x1", response.Get("result"), "Should be equal")
+	assert.Equal(suite.T(), 0, second.count("eth_blockNumber"), "Should be equal")
+}
+
+func (suite *QuorumTestSuite) Test_Batch() {
+	first := &stubBatchProvider{stubProvider: answering("0x1", nil)}
+	q := suite.quorum(first, answering("0x1", nil), answering("0x1", nil))
+	method := q.GetRPCMethod()
+
+	_, err := q.SendBatch([]rpc.Request{method.NewRequest("eth_blockNumber"), method.NewRequest("eth_gasPrice")})
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), 1, first.batches, "Should be equal")
+
+	responses, err := q.SendBatch([]rpc.Request{method.NewRequest("eth_blockNumber"), method.NewRequest("eth_getBalance")})
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Len(suite.T(), responses, 2, "Should be equal")
+	assert.Equal(suite.T(), 1, first.batches, "Should be equal")
+	assert.Equal(suite.T(), 1, first.count("eth_getBalance"), "Should be equal")
+}
+
+func (suite *QuorumTestSuite) Test_IsConnected() {
+	down := answering("0x1", nil)
+	down.connected = false
+	assert.True(suite.T(), suite.quorum(answering("0x1", nil), answering("0x1", nil), down).IsConnected(), "Should be true")
+	assert.False(suite.T(), suite.quorum(answering("0x1", nil), down, down).IsConnected(), "Should be false")
+}
+
+func (suite *QuorumTestSuite) SetupTest() {
+	suite.disagreements = make(chan []error, 10)
+}
+
+func Test_QuorumTestSuite(t *testing.T) {
+	suite.Run(t, new(QuorumTestSuite))
+}
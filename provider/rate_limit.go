@@ -0,0 +1,236 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/alanchchen/web3go/rpc"
+)
+
+var (
+	// ErrRateLimited is returned when a request exceeds the limits in
+	// fail fast mode, or waited longer than the timeout.
+	ErrRateLimited = errors.New("Rate limit exceeded")
+)
+
+// LimitMode decides what happens to a request exceeding the limits.
+type LimitMode int
+
+const (
+	// LimitBlock waits until the request fits in the limits.
+	LimitBlock LimitMode = iota
+	// LimitFailFast fails the request with ErrRateLimited.
+	LimitFailFast
+)
+
+// RateLimitOption configures the RateLimit middleware. Zero fields take
+// defaults.
+type RateLimitOption struct {
+	// Rate is the number of request units allowed per second, unlimited if
+	// zero.
+	Rate float64
+	// Burst is the number of units that can be spent at once, Rate rounded
+	// up by default.
+	Burst int
+	// MaxInFlight is the number of requests sent at once, unlimited if
+	// zero. A batch counts as one request.
+	MaxInFlight int
+	// Weights are the units spent by methods, 1 for methods not listed. A
+	// batch spends the units of all its requests.
+	Weights map[string]int
+	// Mode is the policy applied to requests exceeding the limits.
+	Mode LimitMode
+	// Context bounds waits in LimitBlock mode, e.g. to the lifetime of the
+	// service. The request fails with the context error once it is done.
+	Context context.Context
+	// Timeout bounds the wait of each request in LimitBlock mode, after
+	// which it fails with ErrRateLimited. No timeout if zero.
+	Timeout time.Duration
+}
+
+// RateLimit returns a middleware limiting the request rate with a token
+// bucket, and the number of requests in flight.
+func RateLimit(option *RateLimitOption) Middleware {
+	l := &limiter{}
+	if option != nil {
+		l.option = *option
+	}
+	if l.option.Context == nil {
+		l.option.Context = context.Background()
+	}
+	if l.option.Rate > 0 {
+		burst := float64(l.option.Burst)
+		if burst <= 0 {
+			burst = math.Ceil(l.option.Rate)
+		}
+		l.bucket = &tokenBucket{rate: l.option.Rate, burst: burst, tokens: burst, last: time.Now()}
+	}
+	if l.option.MaxInFlight > 0 {
+		l.slots = make(chan struct{}, l.option.MaxInFlight)
+	}
+
+	send := func(request rpc.Request, next SendFunc) (rpc.Response, error) {
+		release, err := l.acquire(l.weight(request))
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return next(request)
+	}
+	batch := func(requests []rpc.Request, next BatchSendFunc) ([]rpc.Response, error) {
+		weight := 0
+		for _, request := range requests {
+			weight += l.weight(request)
+		}
+		release, err := l.acquire(weight)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return next(requests)
+	}
+	return Intercept(send, batch)
+}
+
+type limiter struct {
+	option RateLimitOption
+	bucket *tokenBucket
+	slots  chan struct{}
+}
+
+func (l *limiter) weight(request rpc.Request) int {
+	method, _ := request.Get("method").(string)
+	if weight, ok := l.option.Weights[method]; ok {
+		return weight
+	}
+	return 1
+}
+
+// acquire spends weight units and takes an in flight slot. The returned
+// function releases the slot. The units are given back if no slot is taken.
+func (l *limiter) acquire(weight int) (func(), error) {
+	ctx := l.option.Context
+	if l.option.Timeout > 0 && l.option.Mode == LimitBlock {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.option.Timeout)
+		defer cancel()
+	}
+
+	if l.bucket != nil {
+		for {
+			ok, delay := l.bucket.take(float64(weight))
+			if ok {
+				break
+			}
+			if l.option.Mode == LimitFailFast {
+				return nil, ErrRateLimited
+			}
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, l.waitError(ctx)
+			}
+		}
+	}
+
+	if l.slots == nil {
+		return func() {}, nil
+	}
+	var err error
+	if l.option.Mode == LimitFailFast {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			err = ErrRateLimited
+		}
+	} else {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			err = l.waitError(ctx)
+		}
+	}
+	if err != nil {
+		if l.bucket != nil {
+			l.bucket.refund(float64(weight))
+		}
+		return nil, err
+	}
+	return func() { <-l.slots }, nil
+}
+
+// waitError tells why the wait bounded by ctx ended.
+func (l *limiter) waitError(ctx context.Context) error {
+	if err := l.option.Context.Err(); err != nil {
+		return err
+	}
+	return ErrRateLimited
+}
+
+type tokenBucket struct {
+	lock   sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// take spends weight tokens if available, otherwise it returns how long to
+// wait before trying again. Weights above the burst are taken from a full
+// bucket, leaving a debt.
+func (b *tokenBucket) take(weight float64) (bool, time.Duration) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	needed := math.Min(weight, b.burst)
+	if b.tokens >= needed {
+		b.tokens -= weight
+		return true, 0
+	}
+	return false, time.Duration((needed - b.tokens) / b.rate * float64(time.Second))
+}
+
+// refund gives back weight tokens taken for a request that was not sent.
+func (b *tokenBucket) refund(weight float64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.tokens = math.Min(b.burst, b.tokens+weight)
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alanchchen/web3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RateLimitTestSuite struct {
+	suite.Suite
+	stub *stubProvider
+}
+
+func (suite *RateLimitTestSuite) send(p Provider, method string) error {
+	_, err := p.Send(p.GetRPCMethod().NewRequest(method))
+	return err
+}
+
+func (suite *RateLimitTestSuite) Test_FailFast() {
+	p := Chain(suite.stub, RateLimit(&RateLimitOption{Rate: 10, Burst: 2, Mode: LimitFailFast}))
+	assert.NoError(suite.T(), suite.send(p, "eth_blockNumber"), "Should be no error")
+	assert.NoError(suite.T(), suite.send(p, "eth_blockNumber"), "Should be no error")
+	assert.Equal(suite.T(), ErrRateLimited, suite.send(p, "eth_blockNumber"), "Should be equal")
+	assert.Equal(suite.T(), 2, suite.stub.count("eth_blockNumber"), "Should be equal")
+
+	time.Sleep(110 * time.Millisecond)
+	assert.NoError(suite.T(), suite.send(p, "eth_blockNumber"), "Should be no error")
+}
+
+func (suite *RateLimitTestSuite) Test_Block() {
+	p := Chain(suite.stub, RateLimit(&RateLimitOption{Rate: 100, Burst: 1}))
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		assert.NoError(suite.T(), suite.send(p, "eth_blockNumber"), "Should be no error")
+	}
+	assert.True(suite.T(), time.Since(start) >= 30*time.Millisecond, "Should wait for tokens")
+}
+
+func (suite *RateLimitTestSuite) Test_Weights() {
+	p := Chain(suite.stub, RateLimit(&RateLimitOption{
+		Rate:    10,
+		Burst:   5,
+		Mode:    LimitFailFast,
+		Weights: map[string]int{"eth_getLogs": 4},
+	}))
+	assert.NoError(suite.T(), suite.send(p, "eth_getLogs"), "Should be no error")
+	assert.NoError(suite.T(), suite.send(p, "eth_blockNumber"), "Should be no error")
+	assert.Equal(suite.T(), ErrRateLimited, suite.send(p, "eth_blockNumber"), "Should be equal")
+}
+
+func (suite *RateLimitTestSuite) Test_HeavyRequest() {
+	p := Chain(suite.stub, RateLimit(&RateLimitOption{
+		Rate:    100,
+		Burst:   2,
+		Mode:    LimitFailFast,
+		Weights: map[string]int{"eth_getLogs": 5},
+	}))
+	// A request heavier than the burst goes through with a full bucket, and
+	// leaves a debt.
+	assert.NoError(suite.T(), suite.send(p, "eth_getLogs"), "Should be no error")
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(suite.T(), ErrRateLimited, suite.send(p, "eth_blockNumber"), "Should be equal")
+}
+
+func (suite *RateLimitTestSuite) Test_Timeout() {
+	p := Chain(suite.stub, RateLimit(&RateLimitOption{Rate: 1, Timeout: 10 * time.Millisecond}))
+	assert.NoError(suite.T(), suite.send(p, "eth_blockNumber"), "Should be no error")
+	assert.Equal(suite.T(), ErrRateLimited, suite.send(p, "eth_blockNumber"), "Should be equal")
+}
+
+func (suite *RateLimitTestSuite) Test_Context() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := Chain(suite.stub, RateLimit(&RateLimitOption{Rate: 1, Context: ctx}))
+	assert.NoError(suite.T(), suite.send(p, "eth_blockNumber"), "Should be no error")
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+	assert.Equal(suite.T(), context.Canceled, suite.send(p, "eth_blockNumber"), "Should be equal")
+}
+
+func (suite *RateLimitTestSuite) Test_MaxInFlight() {
+	release := make(chan struct{})
+	var lock sync.Mutex
+	inFlight, peak := 0, 0
+	stub := newStubProvider(func(request rpc.Request) (interface{}, error) {
+		lock.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		lock.Unlock()
+		<-release
+		lock.Lock()
+		inFlight--
+		lock.Unlock()
+		return nil, nil
+	})
+	p := Chain(stub, RateLimit(&RateLimitOption{MaxInFlight: 2}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			suite.send(p, "eth_call")
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(suite.T(), 2, peak, "Should be equal")
+
+	blocked := make(chan struct{})
+	stub.handle = func(request rpc.Request) (interface{}, error) {
+		<-blocked
+		return nil, nil
+	}
+	p = Chain(stub, RateLimit(&RateLimitOption{MaxInFlight: 1, Mode: LimitFailFast}))
+	go suite.send(p, "eth_call")
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(suite.T(), ErrRateLimited, suite.send(p, "eth_call"), "Should be equal")
+	close(blocked)
+}
+
+func (suite *RateLimitTestSuite) Test_Refund() {
+	blocked := make(chan struct{})
+	stub := newStubProvider(func(request rpc.Request) (interface{}, error) {
+		<-blocked
+		return nil, nil
+	})
+	p := Chain(stub, RateLimit(&RateLimitOption{Rate: 1, Burst: 2, MaxInFlight: 1, Mode: LimitFailFast}))
+	done := make(chan error)
+	go func() { done <- suite.send(p, "eth_call") }()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(suite.T(), ErrRateLimited, suite.send(p, "eth_call"), "Should be equal")
+	close(blocked)
+	assert.NoError(suite.T(), <-done, "Should be no error")
+
+	// The rejected request gave its token back.
+	assert.NoError(suite.T(), suite.send(p, "eth_call"), "Should be no error")
+}
+
+func (suite *RateLimitTestSuite) Test_Batch() {
+	stub := &stubBatchProvider{stubProvider: suite.stub}
+	p := Chain(stub, RateLimit(&RateLimitOption{Rate: 10, Burst: 3, Mode: LimitFailFast})).(BatchProvider)
+	method := p.GetRPCMethod()
+
+	_, err := p.SendBatch([]rpc.Request{method.NewRequest("eth_a"), method.NewRequest("eth_b")})
+	assert.NoError(suite.T(), err, "Should be no error")
+	_, err = p.SendBatch([]rpc.Request{method.NewRequest("eth_a"), method.NewRequest("eth_b")})
+	assert.Equal(suite.T(), ErrRateLimited, err, "Should be equal")
+}
+
+func (suite *RateLimitTestSuite) SetupTest() {
+	suite.stub = newStubProvider(echo)
+}
+
+func Test_RateLimitTestSuite(t *testing.T) {
+	suite.Run(t, new(RateLimitTestSuite))
+}
@@ -0,0 +1,275 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/alanchchen/web3go/rpc"
+)
+
+// exchange is a line of a fixture: a request and the response to it.
+type exchange struct {
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response"`
+}
+
+// recordedResponse is a recorded response, its result is kept as it was
+// sent.
+type recordedResponse struct {
+	Result json.RawMessage   `json:"result"`
+	Err    *rpc.JSONRPCError `json:"error"`
+}
+
+// canonicalParams returns the JSON of params with object keys sorted, so
+// parameters sent as structs match their recorded form.
+func canonicalParams(params interface{}) (string, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return "", err
+	}
+	if value == nil {
+		value = []interface{}{}
+	}
+	data, err = json.Marshal(value)
+	return string(data), err
+}
+
+// Recorder writes the requests sent through it and their responses to a
+// fixture, one JSON object per line, to be replayed by a Replayer. Requests
+// failing without a response are not recorded.
+type Recorder struct {
+	lock sync.Mutex
+	w    io.Writer
+	err  error
+}
+
+// NewRecorder creates a recorder writing to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{w: w}
+}
+
+// Err returns the first error writing the fixture.
+func (r *Recorder) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.err
+}
+
+// Wrap returns next with its traffic recorded, it is a Middleware.
+func (r *Recorder) Wrap(next Provider) Provider {
+	send := func(request rpc.Request, next SendFunc) (rpc.Response, error) {
+		response, err := next(request)
+		if err == nil {
+			r.record(request, response)
+		}
+		return response, err
+	}
+	batch := func(requests []rpc.Request, next BatchSendFunc) ([]rpc.Response, error) {
+		responses, err := next(requests)
+		if err == nil {
+			for i := range responses {
+				r.record(requests[i], responses[i])
+			}
+		}
+		return responses, err
+	}
+	return Intercept(send, batch)(next)
+}
+
+func (r *Recorder) record(request rpc.Request, response rpc.Response) {
+	data, err := json.Marshal(&exchange{
+		Request:  json.RawMessage(request.String()),
+		Response: json.RawMessage(response.String()),
+	})
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.err != nil {
+		return
+	}
+	if err == nil {
+		_, err = r.w.Write(append(data, '\n'))
+	}
+	r.err = err
+}
+
+// UnexpectedRequestError is returned by a Replayer for a request missing
+// from its fixture.
+type UnexpectedRequestError struct {
+	Method string
+	Params string
+}
+
+func (err *UnexpectedRequestError) Error() string {
+	return fmt.Sprintf("Unexpected request %s %s", err.Method, err.Params)
+}
+
+// ReplayOption configures a Replayer.
+type ReplayOption struct {
+	// RepeatLast answers the requests sent more often than recorded with
+	// their last response, instead of failing. Useful for polled methods.
+	RepeatLast bool
+}
+
+// Replayer answers requests from a fixture written by a Recorder, matching
+// them by method and parameters. Identical requests get their recorded
+// responses in order. Responses carry the id of the request they answer.
+type Replayer struct {
+	option ReplayOption
+	rpc    rpc.RPC
+
+	lock      sync.Mutex
+	responses map[string][]*recordedResponse
+	last      map[string]*recordedResponse
+}
+
+// NewReplayer reads a fixture from r.
+func NewReplayer(r io.Reader, option *ReplayOption) (*Replayer, error) {
+	p := &Replayer{
+		rpc:       rpc.GetDefaultMethod(),
+		responses: make(map[string][]*recordedResponse),
+		last:      make(map[string]*recordedResponse),
+	}
+	if option != nil {
+		p.option = *option
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e exchange
+		request := &rpc.JSONRPCRequest{}
+		response := &recordedResponse{}
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("Malformed fixture line %d, %v", line, err)
+		}
+		if err := json.Unmarshal(e.Request, request); err != nil {
+			return nil, fmt.Errorf("Malformed fixture request line %d, %v", line, err)
+		}
+		if err := json.Unmarshal(e.Response, response); err != nil {
+			return nil, fmt.Errorf("Malformed fixture response line %d, %v", line, err)
+		}
+		params, err := canonicalParams(request.Params)
+		if err != nil {
+			return nil, err
+		}
+		key := request.Method + params
+		p.responses[key] = append(p.responses[key], response)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// LoadReplayer reads the fixture file at path.
+func LoadReplayer(path string, option *ReplayOption) (*Replayer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return NewReplayer(file, option)
+}
+
+// Pending returns the number of recorded responses not replayed yet.
+func (p *Replayer) Pending() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	pending := 0
+	for _, responses := range p.responses {
+		pending += len(responses)
+	}
+	return pending
+}
+
+// IsConnected ...
+func (p *Replayer) IsConnected() bool {
+	return true
+}
+
+// Send answers request with its next recorded response.
+func (p *Replayer) Send(request rpc.Request) (rpc.Response, error) {
+	method, _ := request.Get("method").(string)
+	params, err := canonicalParams(requestParams(request))
+	if err != nil {
+		return nil, err
+	}
+	key := method + params
+
+	p.lock.Lock()
+	response := p.last[key]
+	if queue := p.responses[key]; len(queue) > 0 {
+		response = queue[0]
+		p.responses[key] = queue[1:]
+		if len(queue) == 1 {
+			delete(p.responses, key)
+		}
+		p.last[key] = response
+	} else if !p.option.RepeatLast {
+		response = nil
+	}
+	p.lock.Unlock()
+
+	if response == nil {
+		return nil, &UnexpectedRequestError{Method: method, Params: params}
+	}
+	return newResponse(p.rpc, request.ID(), response.Result, response.Err), nil
+}
+
+// SendBatch answers requests one by one.
+func (p *Replayer) SendBatch(requests []rpc.Request) ([]rpc.Response, error) {
+	responses := make([]rpc.Response, len(requests))
+	for i, request := range requests {
+		response, err := p.Send(request)
+		if err != nil {
+			return nil, err
+		}
+		responses[i] = response
+	}
+	return responses, nil
+}
+
+func (p *Replayer) GetRPCMethod() rpc.RPC {
+	return p.rpc
+}
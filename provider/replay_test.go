@@ -0,0 +1,201 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alanchchen/web3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// testCall is a parameter sent as a struct, its fields are not in key order.
+type testCall struct {
+	To   string `json:"to"`
+	Data string `json:"data"`
+}
+
+type ReplayTestSuite struct {
+	suite.Suite
+	buffer   *bytes.Buffer
+	recorder Provider
+	balance  int
+}
+
+func (suite *ReplayTestSuite) send(p Provider, method string, params ...interface{}) (rpc.Response, error) {
+	request := p.GetRPCMethod().NewRequest(method)
+	request.Set("params", params)
+	response, err := p.Send(request)
+	if err == nil {
+		assert.Equal(suite.T(), request.ID(), response.ID(), "Should be equal")
+	}
+	return response, err
+}
+
+func (suite *ReplayTestSuite) replayer(option *ReplayOption) *Replayer {
+	replayer, err := NewReplayer(bytes.NewReader(suite.buffer.Bytes()), option)
+	assert.NoError(suite.T(), err, "Should be no error")
+	return replayer
+}
+
+func (suite *ReplayTestSuite) Test_Replay() {
+	suite.send(suite.recorder, "eth_getBalance", testAddress, "latest")
+	suite.send(suite.recorder, "eth_getBalance", testAddress, "latest")
+	suite.send(suite.recorder, "eth_call", &testCall{To: testAddress, Data: "0x01"}, "latest")
+	suite.send(suite.recorder, "eth_getTransactionReceipt", testTxHash)
+	assert.Equal(suite.T(), 4, strings.Count(suite.buffer.String(), "\n"), "Should be equal")
+
+	replayer := suite.replayer(nil)
+	assert.Equal(suite.T(), 4, replayer.Pending(), "Should be equal")
+
+	response, err := suite.send(replayer, "eth_getBalance", testAddress, "latest")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), "0x1", response.Get("result"), "Should be equal")
+	response, err = suite.send(replayer, "eth_getBalance", testAddress, "latest")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), "0x2", response.Get("result"), "Should be equal")
+
+	response, err = suite.send(replayer, "eth_call", map[string]interface{}{"data": "0x01", "to": testAddress}, "latest")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), "0xcafe", response.Get("result"), "Should be equal")
+
+	response, err = suite.send(replayer, "eth_getTransactionReceipt", testTxHash)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualError(suite.T(), response.Error(), "Unknown transaction", "Should be equal")
+	assert.Equal(suite.T(), 0, replayer.Pending(), "Should be equal")
+}
+
+func (suite *ReplayTestSuite) Test_Unexpected() {
+	suite.send(suite.recorder, "eth_getBalance", testAddress, "latest")
+	replayer := suite.replayer(nil)
+
+	_, err := suite.send(replayer, "eth_getBalance", testAddress, "0x10")
+	if assert.IsType(suite.T(), &UnexpectedRequestError{}, err, "Should be an unexpected request") {
+		assert.Equal(suite.T(), "eth_getBalance", err.(*UnexpectedRequestError).Method, "Should be equal")
+	}
+	suite.send(replayer, "eth_getBalance", testAddress, "latest")
+	_, err = suite.send(replayer, "eth_getBalance", testAddress, "latest")
+	assert.IsType(suite.T(), &UnexpectedRequestError{}, err, "Should be an unexpected request")
+}
+
+func (suite *ReplayTestSuite) Test_RepeatLast() {
+	suite.send(suite.recorder, "eth_getBalance", testAddress, "latest")
+	suite.send(suite.recorder, "eth_getBalance", testAddress, "latest")
+	replayer := suite.replayer(&ReplayOption{RepeatLast: true})
+
+	for _, expected := range []string{"0x1", "0x2", "0x2"} {
+		response, err := suite.send(replayer, "eth_getBalance", testAddress, "latest")
+		assert.NoError(suite.T(), err, "Should be no error")
+		assert.Equal(suite.T(), expected, response.Get("result"), "Should be equal")
+	}
+	_, err := suite.send(replayer, "eth_blockNumber")
+	assert.Error(suite.T(), err, "Should be error")
+}
+
+func (suite *ReplayTestSuite) Test_Fixture() {
+	suite.recorder = NewRecorder(suite.buffer).Wrap(answering(json.Number("123456789012345678901234567890"), nil))
+	request := suite.recorder.GetRPCMethod().NewRequest("eth_getBalance")
+	request.Set("params", []interface{}{testAddress, "0x1"})
+	suite.recorder.Send(request)
+
+	var e exchange
+	assert.NoError(suite.T(), json.Unmarshal(suite.buffer.Bytes(), &e), "Should be no error")
+	assert.JSONEq(suite.T(), request.String(), string(e.Request), "Should be equal")
+	assert.Contains(suite.T(), string(e.Response), `"result":123456789012345678901234567890`, "Should keep the result as sent")
+}
+
+func (suite *ReplayTestSuite) Test_Batch() {
+	stub := &stubBatchProvider{stubProvider: newStubProvider(echo)}
+	recorder := NewRecorder(suite.buffer).Wrap(stub).(BatchProvider)
+	method := recorder.GetRPCMethod()
+	_, err := recorder.SendBatch([]rpc.Request{method.NewRequest("eth_chainId"), method.NewRequest("eth_blockNumber")})
+	assert.NoError(suite.T(), err, "Should be no error")
+
+	responses, err := suite.replayer(nil).SendBatch([]rpc.Request{method.NewRequest("eth_blockNumber"), method.NewRequest("eth_chainId")})
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), "eth_blockNumber", responses[0].Get("result"), "Should be equal")
+	assert.Equal(suite.T(), "eth_chainId", responses[1].Get("result"), "Should be equal")
+}
+
+func (suite *ReplayTestSuite) Test_Failures() {
+	failing := NewRecorder(suite.buffer).Wrap(answering(nil, errors.New("Connection refused")))
+	_, err := suite.send(failing, "eth_chainId")
+	assert.Error(suite.T(), err, "Should be error")
+	assert.Equal(suite.T(), 0, suite.buffer.Len(), "Should not record failures")
+
+	_, err = NewReplayer(strings.NewReader("{\"request\":42}\n"), nil)
+	assert.Error(suite.T(), err, "Should be error")
+}
+
+func (suite *ReplayTestSuite) Test_LoadReplayer() {
+	suite.send(suite.recorder, "eth_getBalance", testAddress, "latest")
+	dir, err := ioutil.TempDir("", "replay")
+	assert.NoError(suite.T(), err, "Should be no error")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fixture.jsonl")
+	assert.NoError(suite.T(), ioutil.WriteFile(path, suite.buffer.Bytes(), 0644), "Should be no error")
+
+	replayer, err := LoadReplayer(path, nil)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), 1, replayer.Pending(), "Should be equal")
+
+	_, err = LoadReplayer(filepath.Join(dir, "missing.jsonl"), nil)
+	assert.Error(suite.T(), err, "Should be error")
+}
+
+func (suite *ReplayTestSuite) SetupTest() {
+	suite.buffer = new(bytes.Buffer)
+	suite.balance = 0
+	stub := newStubProvider(func(request rpc.Request) (interface{}, error) {
+		switch request.Get("method").(string) {
+		case "eth_getBalance":
+			suite.balance++
+			return "0x" + string(rune('0'+suite.balance)), nil
+		case "eth_call":
+			return "0xcafe", nil
+		case "eth_getTransactionReceipt":
+			return nil, &rpc.JSONRPCError{Code: codeServerError, Message: "Unknown transaction"}
+		}
+		return echo(request)
+	})
+	suite.recorder = NewRecorder(suite.buffer).Wrap(stub)
+}
+
+func Test_ReplayTestSuite(t *testing.T) {
+	suite.Run(t, new(ReplayTestSuite))
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"encoding/json"
+
+	"github.com/alanchchen/web3go/rpc"
+)
+
+// codeServerError is the generic error code of Ethereum nodes, sent with
+// errors such as "filter not found".
+const codeServerError = -32000
+
+// newResponse builds the response to request id, with result or rpcErr.
+func newResponse(method rpc.RPC, id uint64, result interface{}, rpcErr *rpc.JSONRPCError) rpc.Response {
+	data, _ := json.Marshal(&rpc.JSONRPCResponse{
+		Version:    "2.0",
+		Identifier: id,
+		Result:     result,
+		Err:        rpcErr,
+	})
+	return method.NewResponse(data)
+}
+
+// copyResponse returns a copy of response answering request id.
+func copyResponse(method rpc.RPC, response rpc.Response, id uint64) rpc.Response {
+	if err := response.Error(); err != nil {
+		rpcErr, ok := err.(*rpc.JSONRPCError)
+		if !ok {
+			code, _ := rpc.ErrorCode(err)
+			rpcErr = &rpc.JSONRPCError{Code: code, Message: err.Error()}
+		}
+		return newResponse(method, id, nil, rpcErr)
+	}
+	return newResponse(method, id, response.Get("result"), nil)
+}
+
+// responseError returns err, or the error of response.
+func responseError(response rpc.Response, err error) error {
+	if err == nil && response != nil {
+		return response.Error()
+	}
+	return err
+}
+
+// requestParams returns the parameters of request.
+func requestParams(request rpc.Request) []interface{} {
+	params, _ := request.Get("params").([]interface{})
+	return params
+}
@@ -0,0 +1,261 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/alanchchen/web3go/rpc"
+)
+
+const (
+	retryAttempts       = 4
+	retryInitialBackoff = 100 * time.Millisecond
+	retryMaxBackoff     = 5 * time.Second
+	retryJitter         = 0.2
+)
+
+// DefaultSafeMethods are the methods retried by default. They only read
+// state, so sending them twice is harmless. eth_getFilterChanges is left out
+// as a lost response would lose the changes.
+var DefaultSafeMethods = []string{
+	"web3_clientVersion",
+	"web3_sha3",
+	"net_version",
+	"net_listening",
+	"net_peerCount",
+	"eth_protocolVersion",
+	"eth_chainId",
+	"eth_syncing",
+	"eth_coinbase",
+	"eth_mining",
+	"eth_hashrate",
+	"eth_gasPrice",
+	"eth_accounts",
+	"eth_blockNumber",
+	"eth_getBalance",
+	"eth_getStorageAt",
+	"eth_getTransactionCount",
+	"eth_getBlockTransactionCountByHash",
+	"eth_getBlockTransactionCountByNumber",
+	"eth_getUncleCountByBlockHash",
+	"eth_getUncleCountByBlockNumber",
+	"eth_getCode",
+	"eth_call",
+	"eth_estimateGas",
+	"eth_getBlockByHash",
+	"eth_getBlockByNumber",
+	"eth_getTransactionByHash",
+	"eth_getTransactionByBlockHashAndIndex",
+	"eth_getTransactionByBlockNumberAndIndex",
+	"eth_getTransactionReceipt",
+	"eth_getUncleByBlockHashAndIndex",
+	"eth_getUncleByBlockNumberAndIndex",
+	"eth_getCompilers",
+	"eth_getFilterLogs",
+	"eth_getLogs",
+	"eth_getWork",
+}
+
+// RetryOption configures the Retry middleware. Zero fields take defaults.
+type RetryOption struct {
+	// MaxAttempts is the number of times a request is sent, 4 by default.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, 100ms by default.
+	// It doubles after each attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts, 5s by default.
+	MaxBackoff time.Duration
+	// Jitter randomly shortens each delay by up to this fraction, 0.2 by
+	// default. Use a negative value to disable jitter.
+	Jitter float64
+	// RetryCodes are more JSON RPC error codes retried for safe methods.
+	// None by default, rpc.CodeLimitExceeded is always retried.
+	RetryCodes []int64
+	// SafeMethods are the methods retried when the node may have processed
+	// the request, DefaultSafeMethods if nil.
+	SafeMethods []string
+}
+
+func (opt *RetryOption) withDefaults() RetryOption {
+	result := RetryOption{}
+	if opt != nil {
+		result = *opt
+	}
+	if result.MaxAttempts <= 0 {
+		result.MaxAttempts = retryAttempts
+	}
+	if result.InitialBackoff <= 0 {
+		result.InitialBackoff = retryInitialBackoff
+	}
+	if result.MaxBackoff <= 0 {
+		result.MaxBackoff = retryMaxBackoff
+	}
+	if result.Jitter == 0 {
+		result.Jitter = retryJitter
+	}
+	if result.Jitter < 0 {
+		result.Jitter = 0
+	}
+	if result.Jitter > 1 {
+		result.Jitter = 1
+	}
+	if result.SafeMethods == nil {
+		result.SafeMethods = DefaultSafeMethods
+	}
+	return result
+}
+
+// Retry returns a middleware resending failed requests with exponential
+// backoff and jitter.
+//
+// Requests the node can't have processed, because the connection failed or
+// the node rate limited them with 429 Too Many Requests or the
+// rpc.CodeLimitExceeded error, are retried whatever the method. Other network
+// errors, 5xx statuses and the RetryCodes are only retried for safe methods,
+// so a transaction is never sent twice. A batch is retried as a whole, and
+// only if all its methods are safe or it wasn't processed. The delay before
+// a retry is at least the Retry-After asked by the node.
+func Retry(option *RetryOption) Middleware {
+	r := &retrier{option: option.withDefaults(), safe: make(map[string]bool)}
+	for _, method := range r.option.SafeMethods {
+		r.safe[method] = true
+	}
+
+	send := func(request rpc.Request, next SendFunc) (response rpc.Response, err error) {
+		safe := r.isSafe(request)
+		for attempt := 1; ; attempt++ {
+			response, err = next(request)
+			if attempt == r.option.MaxAttempts || !r.shouldRetry(safe, response, err) {
+				return response, err
+			}
+			time.Sleep(r.delay(attempt, err))
+		}
+	}
+	batch := func(requests []rpc.Request, next BatchSendFunc) (responses []rpc.Response, err error) {
+		safe := true
+		for _, request := range requests {
+			safe = safe && r.isSafe(request)
+		}
+		for attempt := 1; ; attempt++ {
+			responses, err = next(requests)
+			if attempt == r.option.MaxAttempts || !r.shouldRetry(safe, nil, err) {
+				return responses, err
+			}
+			time.Sleep(r.delay(attempt, err))
+		}
+	}
+	return Intercept(send, batch)
+}
+
+type retrier struct {
+	option RetryOption
+	safe   map[string]bool
+}
+
+func (r *retrier) isSafe(request rpc.Request) bool {
+	method, _ := request.Get("method").(string)
+	return r.safe[method]
+}
+
+func (r *retrier) shouldRetry(safe bool, response rpc.Response, err error) bool {
+	if err == nil {
+		if response == nil || response.Error() == nil {
+			return false
+		}
+		code, _ := rpc.ErrorCode(response.Error())
+		if code == rpc.CodeLimitExceeded {
+			return true
+		}
+		if !safe {
+			return false
+		}
+		for _, c := range r.option.RetryCodes {
+			if c == code {
+				return true
+			}
+		}
+		return false
+	}
+
+	if notProcessed(err) {
+		return true
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return safe && httpErr.StatusCode >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return safe && errors.As(err, &netErr)
+}
+
+// delay returns the delay after the given attempt failed with err, the
+// backoff or the Retry-After of an HTTP error if longer.
+func (r *retrier) delay(attempt int, err error) time.Duration {
+	delay := r.backoff(attempt)
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.RetryAfter > delay {
+		delay = httpErr.RetryAfter
+	}
+	return delay
+}
+
+// backoff returns the delay after the given attempt.
+func (r *retrier) backoff(attempt int) time.Duration {
+	delay := r.option.InitialBackoff
+	for i := 1; i < attempt && delay < r.option.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.option.MaxBackoff {
+		delay = r.option.MaxBackoff
+	}
+	return delay - time.Duration(rand.Float64()*r.option.Jitter*float64(delay))
+}
+
+// notProcessed tells if err proves the node didn't process the request.
+func notProcessed(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		if httpErr.StatusCode == http.StatusTooManyRequests {
+			return true
+		}
+		if httpErr.Response != nil && httpErr.Response.Error() != nil {
+			code, _ := rpc.ErrorCode(httpErr.Response.Error())
+			return code == rpc.CodeLimitExceeded
+		}
+		return false
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
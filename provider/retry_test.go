@@ -0,0 +1,189 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alanchchen/web3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// failing returns a handler failing count times with err before echoing.
+func failing(count int, err error) func(request rpc.Request) (interface{}, error) {
+	return func(request rpc.Request) (interface{}, error) {
+		if count > 0 {
+			count--
+			return nil, err
+		}
+		return echo(request)
+	}
+}
+
+type RetryTestSuite struct {
+	suite.Suite
+	option *RetryOption
+}
+
+func (suite *RetryTestSuite) send(stub *stubProvider, method string) (rpc.Response, error) {
+	p := Chain(stub, Retry(suite.option))
+	return p.Send(p.GetRPCMethod().NewRequest(method))
+}
+
+func (suite *RetryTestSuite) Test_RetrySafeMethod() {
+	stub := newStubProvider(failing(2, &HTTPError{StatusCode: http.StatusBadGateway}))
+	response, err := suite.send(stub, "eth_blockNumber")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), "eth_blockNumber", response.Get("result"), "Should be equal")
+	assert.Equal(suite.T(), 3, stub.count("eth_blockNumber"), "Should be equal")
+}
+
+func (suite *RetryTestSuite) Test_MaxAttempts() {
+	failure := &HTTPError{StatusCode: http.StatusServiceUnavailable}
+	stub := newStubProvider(failing(10, failure))
+	_, err := suite.send(stub, "eth_blockNumber")
+	assert.Equal(suite.T(), failure, err, "Should be equal")
+	assert.Equal(suite.T(), 3, stub.count("eth_blockNumber"), "Should be equal")
+}
+
+func (suite *RetryTestSuite) Test_UnsafeMethod() {
+	stub := newStubProvider(failing(1, &HTTPError{StatusCode: http.StatusBadGateway}))
+	_, err := suite.send(stub, "eth_sendRawTransaction")
+	assert.IsType(suite.T(), &HTTPError{}, err, "Should be an HTTP error")
+	assert.Equal(suite.T(), 1, stub.count("eth_sendRawTransaction"), "Should be equal")
+
+	stub = newStubProvider(failing(1, &net.OpError{Op: "read", Err: errors.New("Connection reset")}))
+	_, err = suite.send(stub, "eth_sendRawTransaction")
+	assert.Error(suite.T(), err, "Should be error")
+	assert.Equal(suite.T(), 1, stub.count("eth_sendRawTransaction"), "Should be equal")
+}
+
+func (suite *RetryTestSuite) Test_NotProcessed() {
+	stub := newStubProvider(failing(1, &HTTPError{StatusCode: http.StatusTooManyRequests}))
+	_, err := suite.send(stub, "eth_sendRawTransaction")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), 2, stub.count("eth_sendRawTransaction"), "Should be equal")
+
+	stub = newStubProvider(failing(1, &net.OpError{Op: "dial", Err: errors.New("Connection refused")}))
+	_, err = suite.send(stub, "eth_sendRawTransaction")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), 2, stub.count("eth_sendRawTransaction"), "Should be equal")
+
+	// Rate limits are reported with JSON RPC errors, with or without an
+	// HTTP error status.
+	limited := &rpc.JSONRPCError{Code: rpc.CodeLimitExceeded, Message: "Rate limited"}
+	stub = newStubProvider(failing(1, limited))
+	response, err := suite.send(stub, "eth_sendRawTransaction")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.NoError(suite.T(), response.Error(), "Should be no error")
+	assert.Equal(suite.T(), 2, stub.count("eth_sendRawTransaction"), "Should be equal")
+
+	failure := &HTTPError{
+		StatusCode: http.StatusServiceUnavailable,
+		Response:   newResponse(stub.GetRPCMethod(), 1, nil, limited),
+	}
+	stub = newStubProvider(failing(1, failure))
+	_, err = suite.send(stub, "eth_sendRawTransaction")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), 2, stub.count("eth_sendRawTransaction"), "Should be equal")
+}
+
+func (suite *RetryTestSuite) Test_RetryAfter() {
+	stub := newStubProvider(failing(1, &HTTPError{StatusCode: http.StatusTooManyRequests, RetryAfter: 30 * time.Millisecond}))
+	start := time.Now()
+	_, err := suite.send(stub, "eth_blockNumber")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.True(suite.T(), time.Since(start) >= 30*time.Millisecond, "Should wait for Retry-After")
+}
+
+func (suite *RetryTestSuite) Test_RetryCodes() {
+	busy := &rpc.JSONRPCError{Code: rpc.CodeInternalError, Message: "Busy"}
+	stub := newStubProvider(failing(1, busy))
+	response, err := suite.send(stub, "eth_getBalance")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), busy, response.Error(), "Should be equal")
+	assert.Equal(suite.T(), 1, stub.count("eth_getBalance"), "Should be equal")
+
+	suite.option.RetryCodes = []int64{rpc.CodeInternalError}
+	stub = newStubProvider(failing(1, busy))
+	response, err = suite.send(stub, "eth_getBalance")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.NoError(suite.T(), response.Error(), "Should be no error")
+	assert.Equal(suite.T(), 2, stub.count("eth_getBalance"), "Should be equal")
+}
+
+func (suite *RetryTestSuite) Test_OtherErrors() {
+	stub := newStubProvider(failing(1, errors.New("Malformed response body")))
+	_, err := suite.send(stub, "eth_blockNumber")
+	assert.Error(suite.T(), err, "Should be error")
+	assert.Equal(suite.T(), 1, stub.count("eth_blockNumber"), "Should be equal")
+}
+
+func (suite *RetryTestSuite) Test_Batch() {
+	stub := &stubBatchProvider{stubProvider: newStubProvider(failing(1, &HTTPError{StatusCode: http.StatusBadGateway}))}
+	p := Chain(stub, Retry(suite.option)).(BatchProvider)
+	method := p.GetRPCMethod()
+
+	responses, err := p.SendBatch([]rpc.Request{method.NewRequest("eth_blockNumber"), method.NewRequest("eth_gasPrice")})
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Len(suite.T(), responses, 2, "Should be equal")
+	assert.Equal(suite.T(), 2, stub.batches, "Should be equal")
+
+	stub.handle = failing(1, &HTTPError{StatusCode: http.StatusBadGateway})
+	_, err = p.SendBatch([]rpc.Request{method.NewRequest("eth_blockNumber"), method.NewRequest("eth_sendRawTransaction")})
+	assert.Error(suite.T(), err, "Should be error")
+	assert.Equal(suite.T(), 3, stub.batches, "Should be equal")
+}
+
+func (suite *RetryTestSuite) Test_Backoff() {
+	r := &retrier{option: (&RetryOption{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Jitter: -1}).withDefaults()}
+	assert.Equal(suite.T(), 100*time.Millisecond, r.backoff(1), "Should be equal")
+	assert.Equal(suite.T(), 400*time.Millisecond, r.backoff(3), "Should be equal")
+	assert.Equal(suite.T(), time.Second, r.backoff(10), "Should be equal")
+
+	r.option.Jitter = 0.5
+	for i := 0; i < 10; i++ {
+		delay := r.backoff(2)
+		assert.True(suite.T(), delay > 100*time.Millisecond && delay <= 200*time.Millisecond, "Should be jittered")
+	}
+}
+
+func (suite *RetryTestSuite) SetupTest() {
+	suite.option = &RetryOption{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+}
+
+func Test_RetryTestSuite(t *testing.T) {
+	suite.Run(t, new(RetryTestSuite))
+}
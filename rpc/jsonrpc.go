@@ -43,6 +43,19 @@ var (
 	version = "2.0"
 )
 
+// Standard JSON-RPC 2.0 error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+
+	// CodeLimitExceeded is returned by Ethereum nodes when a request exceeds
+	// a server side limit (EIP-1474).
+	CodeLimitExceeded = -32005
+)
+
 // JSONRPCRequest ...
 type JSONRPCRequest struct {
 	Version    string        `json:"jsonrpc,omitempty"`
@@ -51,7 +64,11 @@ type JSONRPCRequest struct {
 	Identifier uint64        `json:"id"`
 }
 
-// Set ...
+// Set sets the method or the params of the request. A slice or array value
+// is spread into the params, each element keeping its own type, so that mixed
+// params such as ["0x1b4", true] of eth_getBlockByNumber, or filter objects,
+// are encoded as they are instead of as strings. Any other value is the only
+// param.
 func (req *JSONRPCRequest) Set(key string, value interface{}) {
 	k := strings.ToLower(key)
 	switch k {
@@ -63,7 +80,7 @@ func (req *JSONRPCRequest) Set(key string, value interface{}) {
 		case reflect.Slice, reflect.Array:
 			v := reflect.ValueOf(value)
 			for i := 0; i < v.Len(); i++ {
-				req.Params = append(req.Params, v.Index(i).String())
+				req.Params = append(req.Params, v.Index(i).Interface())
 			}
 		default:
 			req.Params = append(req.Params, value)
@@ -111,6 +128,11 @@ func (err *JSONRPCError) Error() string {
 	return err.Message
 }
 
+// ErrorCode ...
+func (err *JSONRPCError) ErrorCode() int64 {
+	return err.Code
+}
+
 // JSONRPCResponse ...
 type JSONRPCResponse struct {
 	Version    string        `json:"jsonrpc"`
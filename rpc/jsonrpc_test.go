@@ -66,6 +66,26 @@ func (suite *JSONRPCTestSuite) Test_NewRequest() {
 	}
 }
 
+func (suite *JSONRPCTestSuite) Test_SetParams() {
+	req := suite.rpc.NewRequest("test")
+
+	req.Set("params", []string{"0x1", "latest"})
+	assert.EqualValues(suite.T(), `{"jsonrpc":"2.0","method":"test","params":["0x1","latest"],"id":1}`, req.String(), "Should be equal")
+
+	req.Set("params", []interface{}{"0x1b4", true})
+	assert.EqualValues(suite.T(), []interface{}{"0x1b4", true}, req.Get("params").([]interface{}), "Should be equal")
+	assert.EqualValues(suite.T(), `{"jsonrpc":"2.0","method":"test","params":["0x1b4",true],"id":1}`, req.String(), "Should be equal")
+
+	filter := &struct {
+		FromBlock string `json:"fromBlock"`
+	}{"0x1"}
+	req.Set("params", [1]interface{}{filter})
+	assert.EqualValues(suite.T(), `{"jsonrpc":"2.0","method":"test","params":[{"fromBlock":"0x1"}],"id":1}`, req.String(), "Should be equal")
+
+	req.Set("params", []int{1, 2})
+	assert.EqualValues(suite.T(), `{"jsonrpc":"2.0","method":"test","params":[1,2],"id":1}`, req.String(), "Should be equal")
+}
+
 func (suite *JSONRPCTestSuite) Test_NewResponse() {
 	rpc := suite.rpc
 	resp := rpc.NewResponse([]byte(`{"jsonrpc": "2.0", "id": 1, "result": ["result1", "result2"]}`))
@@ -99,6 +119,19 @@ func (suite *JSONRPCTestSuite) Test_NewResponse() {
 	assert.Nil(suite.T(), resp)
 }
 
+func (suite *JSONRPCTestSuite) Test_ErrorCode() {
+	rpc := suite.rpc
+	resp := rpc.NewResponse([]byte(`{"jsonrpc": "2.0", "id": 1, "error": {"code": -32601, "message": "not found"}}`))
+	if assert.NotNil(suite.T(), resp) {
+		code, ok := ErrorCode(resp.Error())
+		assert.True(suite.T(), ok, "Should be true")
+		assert.EqualValues(suite.T(), CodeMethodNotFound, code, "Should be equal")
+	}
+
+	_, ok := ErrorCode(nil)
+	assert.False(suite.T(), ok, "Should be false")
+}
+
 func (suite *JSONRPCTestSuite) SetupTest() {
 	suite.rpc = NewJSONRPC()
 }
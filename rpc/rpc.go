@@ -56,3 +56,14 @@ type RPC interface {
 func GetDefaultMethod() RPC {
 	return NewJSONRPC()
 }
+
+// ErrorCode returns the code carried by an RPC error, e.g. CodeMethodNotFound.
+// The second return value is false if err is not an RPC error.
+func ErrorCode(err error) (int64, bool) {
+	if coder, ok := err.(interface {
+		ErrorCode() int64
+	}); ok {
+		return coder.ErrorCode(), true
+	}
+	return 0, false
+}
@@ -32,7 +32,9 @@ package test
 import (
 	"encoding/json"
 	"fmt"
+	"math/big"
 
+	"github.com/alanchchen/web3go/common"
 	"github.com/alanchchen/web3go/rpc"
 )
 
@@ -55,3 +57,136 @@ func generateResponse(rpc rpc.RPC, request rpc.Request, result interface{}) (res
 	}
 	return nil, fmt.Errorf("Failed to generate response")
 }
+
+func generateErrorResponse(rpc rpc.RPC, request rpc.Request, code int64, message string) (response rpc.Response, err error) {
+	data := struct {
+		Version string `json:"jsonrpc"`
+		ID      uint64 `json:"id"`
+		Error   struct {
+			Code    int64  `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}{
+		Version: request.Get("version").(string),
+		ID:      request.ID(),
+	}
+	data.Error.Code = code
+	data.Error.Message = message
+	rawData, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	if resp := rpc.NewResponse(rawData); resp != nil {
+		return resp, nil
+	}
+	return nil, fmt.Errorf("Failed to generate response")
+}
+
+func methodNotFound(r rpc.RPC, request rpc.Request) (response rpc.Response, err error) {
+	message := fmt.Sprintf("The method %v does not exist/is not available", request.Get("method"))
+	return generateErrorResponse(r, request, rpc.CodeMethodNotFound, message)
+}
+
+// decodeParam decodes the parameter at index of a request into v.
+func decodeParam(request rpc.Request, index int, v interface{}) error {
+	params, _ := request.Get("params").([]interface{})
+	if index >= len(params) {
+		return fmt.Errorf("Missing parameter %d", index)
+	}
+	rawData, err := json.Marshal(params[index])
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(rawData, v)
+}
+
+// NodeLogs encodes logs the way nodes send them, with hex strings.
+func NodeLogs(logs []common.Log) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(logs))
+	for _, log := range logs {
+		topics := make([]string, 0, len(log.Topics))
+		for _, topic := range log.Topics {
+			topics = append(topics, common.EncodeHex(topic.Data))
+		}
+		result = append(result, map[string]interface{}{
+			"logIndex":         common.EncodeUint64Quantity(log.LogIndex),
+			"blockNumber":      nodeQuantity(log.BlockNumber),
+			"blockHash":        common.EncodeHex(log.BlockHash[:]),
+			"transactionHash":  common.EncodeHex(log.TransactionHash[:]),
+			"transactionIndex": common.EncodeUint64Quantity(log.TransactionIndex),
+			"address":          common.EncodeHex(log.Address[:]),
+			"data":             common.EncodeHex(log.Data),
+			"topics":           topics,
+			"removed":          log.Removed,
+		})
+	}
+	return result
+}
+
+// NodeTransaction encodes a transaction the way nodes send it, with hex
+// strings. A transaction without block number is pending, its block fields
+// are null.
+func NodeTransaction(tx *common.Transaction) map[string]interface{} {
+	result := map[string]interface{}{
+		"hash":             common.EncodeHex(tx.Hash[:]),
+		"nonce":            common.EncodeQuantity(new(big.Int).SetBytes(tx.Nonce[:])),
+		"blockHash":        nil,
+		"blockNumber":      nil,
+		"transactionIndex": nil,
+		"from":             common.EncodeHex(tx.From[:]),
+		"to":               nil,
+		"gas":              nodeQuantity(tx.Gas),
+		"gasPrice":         nodeQuantity(tx.GasPrice),
+		"value":            nodeQuantity(tx.Value),
+		"input":            common.EncodeHex(tx.Data),
+	}
+	if tx.BlockNumber != nil {
+		result["blockHash"] = common.EncodeHex(tx.BlockHash[:])
+		result["blockNumber"] = common.EncodeQuantity(tx.BlockNumber)
+		result["transactionIndex"] = common.EncodeUint64Quantity(tx.TransactionIndex)
+	}
+	if tx.To != (common.Address{}) {
+		result["to"] = common.EncodeHex(tx.To[:])
+	}
+	return result
+}
+
+// NodeBlock encodes a block the way nodes send it, with hex strings.
+// Transactions are listed by hash.
+func NodeBlock(block *common.Block) map[string]interface{} {
+	return map[string]interface{}{
+		"number":           nodeQuantity(block.Number),
+		"hash":             common.EncodeHex(block.Hash[:]),
+		"parentHash":       common.EncodeHex(block.ParentHash[:]),
+		"nonce":            common.EncodeHex(block.Nonce[:]),
+		"sha3Uncles":       common.EncodeHex(block.Sha3Uncles[:]),
+		"logsBloom":        common.EncodeHex(block.Bloom[:]),
+		"transactionsRoot": common.EncodeHex(block.TransactionRoot[:]),
+		"stateRoot":        common.EncodeHex(block.StateRoot[:]),
+		"miner":            common.EncodeHex(block.Miner[:]),
+		"difficulty":       nodeQuantity(block.Difficulty),
+		"totalDifficulty":  nodeQuantity(block.TotalDifficulty),
+		"extraData":        common.EncodeHex(block.ExtraData[:]),
+		"size":             nodeQuantity(block.Size),
+		"gasLimit":         nodeQuantity(block.GasLimit),
+		"gasUsed":          nodeQuantity(block.GasUsed),
+		"timestamp":        nodeQuantity(block.Timestamp),
+		"transactions":     nodeHashes(block.Transactions),
+		"uncles":           nodeHashes(block.Uncles),
+	}
+}
+
+func nodeHashes(hashes []common.Hash) []string {
+	result := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		result = append(result, common.EncodeHex(hash[:]))
+	}
+	return result
+}
+
+func nodeQuantity(i *big.Int) interface{} {
+	if i == nil {
+		return nil
+	}
+	return common.EncodeQuantity(i)
+}
@@ -30,7 +30,6 @@
 package test
 
 import (
-	"fmt"
 	"math/big"
 
 	"github.com/alanchchen/web3go/common"
@@ -53,6 +52,8 @@ func (eth *MockEthAPI) Do(request rpc.Request) (response rpc.Response, err error
 	switch method {
 	case "eth_protocolVersion":
 		return generateResponse(eth.rpc, request, "54")
+	case "eth_chainId":
+		return generateResponse(eth.rpc, request, "0x1")
 	case "eth_syncing":
 		return generateResponse(eth.rpc, request, false)
 	case "eth_coinbase":
@@ -116,7 +117,7 @@ func (eth *MockEthAPI) Do(request rpc.Request) (response rpc.Response, err error
 			Transactions:    []common.Hash{},
 			Uncles:          []common.Hash{},
 		}
-		return generateResponse(eth.rpc, request, block)
+		return generateResponse(eth.rpc, request, NodeBlock(block))
 	case "eth_getBlockByNumber":
 		block := &common.Block{
 			Number:          big.NewInt(0x1b4),
@@ -138,7 +139,7 @@ func (eth *MockEthAPI) Do(request rpc.Request) (response rpc.Response, err error
 			Transactions:    []common.Hash{},
 			Uncles:          []common.Hash{},
 		}
-		return generateResponse(eth.rpc, request, block)
+		return generateResponse(eth.rpc, request, NodeBlock(block))
 	case "eth_getTransactionByHash":
 		tx := &common.Transaction{
 			Hash:             common.NewHash(common.HexToBytes("0xc6ef2fc5426d6ad6fd9e2a26abeab0aa2411b7ab17f30a99d3cb96aed1d1055b")),
@@ -153,7 +154,7 @@ func (eth *MockEthAPI) Do(request rpc.Request) (response rpc.Response, err error
 			GasPrice:         big.NewInt(0x09184e72a000),
 			Data:             common.HexToBytes("0x603880600c6000396000f300603880600c6000396000f3603880600c6000396000f360"),
 		}
-		return generateResponse(eth.rpc, request, tx)
+		return generateResponse(eth.rpc, request, NodeTransaction(tx))
 	case "eth_getTransactionByBlockHashAndIndex":
 		tx := &common.Transaction{
 			Hash:             common.NewHash(common.HexToBytes("0xc6ef2fc5426d6ad6fd9e2a26abeab0aa2411b7ab17f30a99d3cb96aed1d1055b")),
@@ -168,7 +169,7 @@ func (eth *MockEthAPI) Do(request rpc.Request) (response rpc.Response, err error
 			GasPrice:         big.NewInt(0x09184e72a000),
 			Data:             common.HexToBytes("0x603880600c6000396000f300603880600c6000396000f3603880600c6000396000f360"),
 		}
-		return generateResponse(eth.rpc, request, tx)
+		return generateResponse(eth.rpc, request, NodeTransaction(tx))
 	case "eth_getTransactionByBlockNumberAndIndex":
 		tx := &common.Transaction{
 			Hash:             common.NewHash(common.HexToBytes("0xc6ef2fc5426d6ad6fd9e2a26abeab0aa2411b7ab17f30a99d3cb96aed1d1055b")),
@@ -183,7 +184,7 @@ func (eth *MockEthAPI) Do(request rpc.Request) (response rpc.Response, err error
 			GasPrice:         big.NewInt(0x09184e72a000),
 			Data:             common.HexToBytes("0x603880600c6000396000f300603880600c6000396000f3603880600c6000396000f360"),
 		}
-		return generateResponse(eth.rpc, request, tx)
+		return generateResponse(eth.rpc, request, NodeTransaction(tx))
 	case "eth_getTransactionReceipt":
 		receipt := &common.TransactionReceipt{
 			Hash:              common.NewHash(common.HexToBytes("0xb903239f8543d04b5dc1ba6579132b143087c68db1b2168786408fcbce568238")),
@@ -217,7 +218,7 @@ func (eth *MockEthAPI) Do(request rpc.Request) (response rpc.Response, err error
 			Transactions:    []common.Hash{},
 			Uncles:          []common.Hash{},
 		}
-		return generateResponse(eth.rpc, request, block)
+		return generateResponse(eth.rpc, request, NodeBlock(block))
 	case "eth_getUncleByBlockNumberAndIndex":
 		block := &common.Block{
 			Number:          big.NewInt(0x1b4),
@@ -239,7 +240,7 @@ func (eth *MockEthAPI) Do(request rpc.Request) (response rpc.Response, err error
 			Transactions:    []common.Hash{},
 			Uncles:          []common.Hash{},
 		}
-		return generateResponse(eth.rpc, request, block)
+		return generateResponse(eth.rpc, request, NodeBlock(block))
 	case "eth_getCompilers":
 		return generateResponse(eth.rpc, request, []string{"solidity", "lll", "serpent"})
 	// case "eth_compileSolidity":
@@ -270,7 +271,7 @@ func (eth *MockEthAPI) Do(request rpc.Request) (response rpc.Response, err error
 				},
 			},
 		}
-		return generateResponse(eth.rpc, request, logs)
+		return generateResponse(eth.rpc, request, NodeLogs(logs))
 	case "eth_getFilterLogs":
 		logs := []common.Log{
 			{
@@ -288,8 +289,12 @@ func (eth *MockEthAPI) Do(request rpc.Request) (response rpc.Response, err error
 				},
 			},
 		}
-		return generateResponse(eth.rpc, request, logs)
+		return generateResponse(eth.rpc, request, NodeLogs(logs))
 	case "eth_getLogs":
+		filter := common.LogFilter{}
+		if err := decodeParam(request, 0, &filter); err != nil {
+			return generateErrorResponse(eth.rpc, request, rpc.CodeInvalidParams, err.Error())
+		}
 		logs := []common.Log{
 			{
 				LogIndex:         0x1,
@@ -306,7 +311,13 @@ func (eth *MockEthAPI) Do(request rpc.Request) (response rpc.Response, err error
 				},
 			},
 		}
-		return generateResponse(eth.rpc, request, logs)
+		matched := make([]common.Log, 0)
+		for i := range logs {
+			if filter.Match(&logs[i]) {
+				matched = append(matched, logs[i])
+			}
+		}
+		return generateResponse(eth.rpc, request, NodeLogs(matched))
 	case "eth_getWork":
 		return generateResponse(eth.rpc, request, []string{
 			"0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef",
@@ -318,5 +329,5 @@ func (eth *MockEthAPI) Do(request rpc.Request) (response rpc.Response, err error
 		// case "eth_submitHashrate":
 	}
 
-	return nil, fmt.Errorf("Invalid method %s", method)
+	return methodNotFound(eth.rpc, request)
 }
@@ -55,8 +55,10 @@ func NewMockHTTPProvider() provider.Provider {
 	method := rpc.GetDefaultMethod()
 	return &MockHTTPProvider{rpc: method,
 		apis: map[string]MockAPI{
-			"net": NewMockNetAPI(method),
-			"eth": NewMockEthAPI(method),
+			"net":  NewMockNetAPI(method),
+			"eth":  NewMockEthAPI(method),
+			"web3": NewMockWeb3API(method),
+			"rpc":  NewMockRPCAPI(method),
 		}}
 }
 
@@ -83,7 +85,19 @@ func (provider *MockHTTPProvider) dispatchMethod(method string, request rpc.Requ
 			return api.Do(request)
 		}
 	}
-	return nil, fmt.Errorf("Unrecognized method %s", method)
+	return methodNotFound(provider.rpc, request)
+}
+
+// SendBatch sends JSON RPC requests one by one, as a batch
+func (provider *MockHTTPProvider) SendBatch(requests []rpc.Request) (responses []rpc.Response, err error) {
+	for _, request := range requests {
+		response, err := provider.Send(request)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
 }
 
 func (provider *MockHTTPProvider) GetRPCMethod() rpc.RPC {
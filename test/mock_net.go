@@ -30,8 +30,6 @@
 package test
 
 import (
-	"fmt"
-
 	"github.com/alanchchen/web3go/rpc"
 )
 
@@ -57,5 +55,5 @@ func (net *MockNetAPI) Do(request rpc.Request) (response rpc.Response, err error
 		return generateResponse(net.rpc, request, "0x32")
 	}
 
-	return nil, fmt.Errorf("Invalid method %s", method)
+	return methodNotFound(net.rpc, request)
 }
@@ -0,0 +1,80 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package test
+
+import (
+	"github.com/alanchchen/web3go/rpc"
+)
+
+// MockWeb3API ...
+type MockWeb3API struct {
+	rpc rpc.RPC
+}
+
+// NewMockWeb3API ...
+func NewMockWeb3API(rpc rpc.RPC) MockAPI {
+	return &MockWeb3API{rpc: rpc}
+}
+
+// Do ...
+func (web3 *MockWeb3API) Do(request rpc.Request) (response rpc.Response, err error) {
+	method := request.Get("method").(string)
+	switch method {
+	case "web3_clientVersion":
+		return generateResponse(web3.rpc, request, "Mock/v0.1.0/linux/go1.6")
+	}
+
+	return methodNotFound(web3.rpc, request)
+}
+
+// MockRPCAPI ...
+type MockRPCAPI struct {
+	rpc rpc.RPC
+}
+
+// NewMockRPCAPI ...
+func NewMockRPCAPI(rpc rpc.RPC) MockAPI {
+	return &MockRPCAPI{rpc: rpc}
+}
+
+// Do ...
+func (r *MockRPCAPI) Do(request rpc.Request) (response rpc.Response, err error) {
+	method := request.Get("method").(string)
+	switch method {
+	case "rpc_modules":
+		return generateResponse(r.rpc, request, map[string]string{
+			"eth":  "1.0",
+			"net":  "1.0",
+			"web3": "1.0",
+		})
+	}
+
+	return methodNotFound(r.rpc, request)
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/alanchchen/web3go/provider"
+	"github.com/alanchchen/web3go/rpc"
+)
+
+// probedMethods are the optional methods checked by ProbeCapabilities. Probes
+// actually run the method, so only cheap ones are listed: txpool support is
+// learnt from txpool_status, txpool_content would download the whole mempool.
+var probedMethods = []string{
+	"eth_chainId",
+	"debug_traceTransaction",
+	"trace_transaction",
+	"txpool_status",
+}
+
+// Capabilities describes what the connected node supports.
+type Capabilities struct {
+	ClientVersion string
+	Namespaces    map[string]bool
+	Methods       map[string]bool
+	Batch         bool
+	Subscriptions bool
+}
+
+// HasNamespace checks if the node serves the given API namespace, e.g. "debug".
+func (c *Capabilities) HasNamespace(namespace string) bool {
+	return c.Namespaces[namespace]
+}
+
+// Supports checks if the node serves the given method. Methods not probed are
+// assumed to be supported when their namespace is.
+func (c *Capabilities) Supports(method string) bool {
+	if supported, ok := c.Methods[method]; ok {
+		return supported
+	}
+	return c.HasNamespace(namespaceOf(method))
+}
+
+// Capabilities returns the capabilities of the connected node. The node is
+// probed on the first call, and the result is cached until the provider
+// changes or ProbeCapabilities is called.
+func (web3 *Web3) Capabilities() (*Capabilities, error) {
+	web3.capabilitiesLock.Lock()
+	defer web3.capabilitiesLock.Unlock()
+
+	if web3.capabilities != nil {
+		return web3.capabilities, nil
+	}
+	return web3.probeCapabilities()
+}
+
+// ProbeCapabilities probes the connected node again and refreshes the cached
+// capabilities.
+func (web3 *Web3) ProbeCapabilities() (*Capabilities, error) {
+	web3.capabilitiesLock.Lock()
+	defer web3.capabilitiesLock.Unlock()
+
+	return web3.probeCapabilities()
+}
+
+func (web3 *Web3) resetCapabilities() {
+	web3.capabilitiesLock.Lock()
+	defer web3.capabilitiesLock.Unlock()
+
+	web3.capabilities = nil
+}
+
+func (web3 *Web3) probeCapabilities() (*Capabilities, error) {
+	version, err := web3.ClientVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Capabilities{
+		ClientVersion: version,
+		Namespaces:    map[string]bool{"web3": true},
+		Methods:       make(map[string]bool),
+	}
+
+	if modules, err := web3.probeModules(); err != nil {
+		return nil, err
+	} else if modules != nil {
+		for namespace := range modules {
+			c.Namespaces[namespace] = true
+		}
+	}
+
+	for _, method := range probedMethods {
+		supported, err := web3.probeMethod(method)
+		if err != nil {
+			return nil, err
+		}
+		c.Methods[method] = supported
+		if supported {
+			c.Namespaces[namespaceOf(method)] = true
+		}
+	}
+
+	c.Subscriptions = web3.probeSubscriptions()
+	c.Batch = web3.probeBatch()
+
+	web3.capabilities = c
+	return c, nil
+}
+
+// probeModules asks the node for its namespaces via rpc_modules, returning nil
+// if the node does not implement it or a gateway rejects it.
+func (web3 *Web3) probeModules() (map[string]interface{}, error) {
+	req := web3.requestManager.newRequest("rpc_modules")
+	resp, err := web3.requestManager.send(req)
+	if isRejected(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if resp.Error() != nil {
+		return nil, nil
+	}
+
+	modules, _ := resp.Get("result").(map[string]interface{})
+	return modules, nil
+}
+
+// probeMethod calls method without parameters. Any answer other than "method
+// not found", including invalid parameter errors, means the method exists.
+// An HTTP error status means a gateway rejected the method, which is then
+// unsupported as far as the client can tell.
+func (web3 *Web3) probeMethod(method string) (bool, error) {
+	req := web3.requestManager.newRequest(method)
+	resp, err := web3.requestManager.send(req)
+	if isRejected(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if code, ok := rpc.ErrorCode(resp.Error()); ok && code == rpc.CodeMethodNotFound {
+		return false, nil
+	}
+	return true, nil
+}
+
+func (web3 *Web3) probeSubscriptions() bool {
+	req := web3.requestManager.newRequest("eth_subscribe")
+	req.Set("params", []string{"newHeads"})
+	resp, err := web3.requestManager.send(req)
+	if err != nil || resp.Error() != nil {
+		return false
+	}
+
+	if id, ok := resp.Get("result").(string); ok {
+		req := web3.requestManager.newRequest("eth_unsubscribe")
+		req.Set("params", []string{id})
+		web3.requestManager.send(req)
+		return true
+	}
+	return false
+}
+
+func (web3 *Web3) probeBatch() bool {
+	req := web3.requestManager.newRequest("web3_clientVersion")
+	resps, err := web3.requestManager.sendBatch([]rpc.Request{req})
+	return err == nil && len(resps) == 1 && resps[0].Error() == nil
+}
+
+// isRejected tells if err is an HTTP error status, as sent by gateways that
+// only allow some methods through.
+func isRejected(err error) bool {
+	var httpErr *provider.HTTPError
+	return errors.As(err, &httpErr)
+}
+
+func namespaceOf(method string) string {
+	if index := strings.Index(method, "_"); index > 0 {
+		return method[:index]
+	}
+	return method
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/alanchchen/web3go/common"
+)
+
+// Checkpoint is the last block processed by a follower.
+type Checkpoint struct {
+	Number *big.Int
+	Hash   common.Hash
+}
+
+// CheckpointStore persists the checkpoint of a follower.
+type CheckpointStore interface {
+	// Load returns the saved checkpoint, nil if there is none.
+	Load() (*Checkpoint, error)
+	Save(checkpoint *Checkpoint) error
+}
+
+// MemoryCheckpointStore keeps the checkpoint in memory, e.g. to share it
+// between followers of the same process.
+type MemoryCheckpointStore struct {
+	lock       sync.Mutex
+	checkpoint *Checkpoint
+}
+
+// NewMemoryCheckpointStore creates an empty in-memory store.
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{}
+}
+
+// Load returns the saved checkpoint, nil if there is none.
+func (s *MemoryCheckpointStore) Load() (*Checkpoint, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.checkpoint == nil {
+		return nil, nil
+	}
+	checkpoint := *s.checkpoint
+	return &checkpoint, nil
+}
+
+// Save replaces the saved checkpoint.
+func (s *MemoryCheckpointStore) Save(checkpoint *Checkpoint) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	saved := *checkpoint
+	s.checkpoint = &saved
+	return nil
+}
+
+// FileCheckpointStore keeps the checkpoint in a JSON file. The file is
+// replaced atomically, a crash leaves either the old or the new checkpoint.
+type FileCheckpointStore struct {
+	path string
+	lock sync.Mutex
+}
+
+type jsonCheckpoint struct {
+	Number string `json:"number"`
+	Hash   string `json:"hash"`
+}
+
+// NewFileCheckpointStore creates a store for the file at path. The file is
+// created on the first Save.
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+// Load reads the checkpoint file, it returns nil if the file doesn't exist.
+func (s *FileCheckpointStore) Load() (*Checkpoint, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	raw := jsonCheckpoint{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	number, err := common.DecodeQuantity(raw.Number)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := common.ParseHash(raw.Hash)
+	if err != nil {
+		return nil, err
+	}
+	return &Checkpoint{Number: number, Hash: hash}, nil
+}
+
+// Save writes the checkpoint to a temporary file, and renames it over the
+// checkpoint file once synced.
+func (s *FileCheckpointStore) Save(checkpoint *Checkpoint) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	data, err := json.Marshal(jsonCheckpoint{
+		Number: common.EncodeQuantity(checkpoint.Number),
+		Hash:   checkpoint.Hash.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alanchchen/web3go/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type CheckpointTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (suite *CheckpointTestSuite) testStore(store CheckpointStore) {
+	checkpoint, err := store.Load()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Nil(suite.T(), checkpoint, "Should be nil")
+
+	saved := &Checkpoint{
+		Number: big.NewInt(0x1b4),
+		Hash:   common.NewHash(common.HexToBytes("0xe670ec64341771606e55d6b4ca35a1a6b75ee3d5145a99d05921026d1527331")),
+	}
+	assert.NoError(suite.T(), store.Save(saved), "Should be no error")
+	saved.Number = big.NewInt(0x1b5)
+	checkpoint, err = store.Load()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), &Checkpoint{Number: big.NewInt(0x1b4), Hash: saved.Hash}, checkpoint, "Should be equal")
+
+	assert.NoError(suite.T(), store.Save(saved), "Should be no error")
+	checkpoint, err = store.Load()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), saved, checkpoint, "Should be equal")
+}
+
+func (suite *CheckpointTestSuite) Test_MemoryStore() {
+	suite.testStore(NewMemoryCheckpointStore())
+}
+
+func (suite *CheckpointTestSuite) Test_FileStore() {
+	path := filepath.Join(suite.dir, "checkpoint.json")
+	suite.testStore(NewFileCheckpointStore(path))
+
+	// A new store on the same file resumes from it.
+	checkpoint, err := NewFileCheckpointStore(path).Load()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), big.NewInt(0x1b5), checkpoint.Number, "Should be equal")
+
+	files, err := ioutil.ReadDir(suite.dir)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Len(suite.T(), files, 1, "Temporary files should be removed")
+}
+
+func (suite *CheckpointTestSuite) Test_FileStoreCorrupted() {
+	path := filepath.Join(suite.dir, "checkpoint.json")
+	assert.NoError(suite.T(), ioutil.WriteFile(path, []byte(`{"number":"0x01"}`), 0644), "Should be no error")
+	_, err := NewFileCheckpointStore(path).Load()
+	assert.Error(suite.T(), err, "Should be an error")
+}
+
+func (suite *CheckpointTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	suite.dir = dir
+}
+
+func (suite *CheckpointTestSuite) TearDownTest() {
+	os.RemoveAll(suite.dir)
+}
+
+func Test_CheckpointTestSuite(t *testing.T) {
+	suite.Run(t, new(CheckpointTestSuite))
+}
@@ -30,6 +30,7 @@
 package web3
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"math/big"
@@ -42,6 +43,7 @@ import (
 // Eth ...
 type Eth interface {
 	ProtocolVersion() (string, error)
+	ChainID() (*big.Int, error)
 	Syncing() (common.SyncStatus, error)
 	Coinbase() (common.Address, error)
 	Mining() (bool, error)
@@ -80,7 +82,16 @@ type Eth interface {
 	UninstallFilter(filter Filter) (bool, error)
 	GetFilterChanges(filter Filter) ([]interface{}, error)
 	GetFilterLogs(filter Filter) ([]interface{}, error)
-	GetLogs(filter Filter) ([]interface{}, error)
+	GetLogs(option *FilterOption) ([]common.Log, error)
+	GetLogsChunked(option *FilterOption, rangeOption *LogRangeOption) ([]common.Log, error)
+	Follow(option *FollowOption) (Follower, error)
+	WatchLogs(option *FilterOption, watch *WatchOption) (LogWatcher, error)
+	WatchBlocks(watch *WatchOption) (HashWatcher, error)
+	WatchBlockHeaders(watch *WatchOption) (HeaderWatcher, error)
+	WatchPendingTransactions(watch *WatchOption) (HashWatcher, error)
+	WatchPendingTransactionDetails(option *PendingTransactionOption) (TransactionWatcher, error)
+	WatchSyncing(watch *WatchOption) (SyncWatcher, error)
+	WaitSynced(ctx context.Context, watch *WatchOption) error
 	GetWork() (common.Hash, common.Hash, common.Hash, error)
 	SubmitWork(nonce uint64, header common.Hash, mixDigest common.Hash) (bool, error)
 	// SubmitHashrate
@@ -112,6 +123,22 @@ func (eth *EthAPI) ProtocolVersion() (string, error) {
 	return resp.Get("result").(string), nil
 }
 
+// ChainID returns the EIP-155 chain id used for signing replay-protected
+// transactions.
+func (eth *EthAPI) ChainID() (*big.Int, error) {
+	req := eth.requestManager.newRequest("eth_chainId")
+	resp, err := eth.requestManager.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error() != nil {
+		return nil, resp.Error()
+	}
+
+	return common.DecodeQuantity(resp.Get("result").(string))
+}
+
 // Syncing returns true with an object with data about the sync status or false
 // with nil.
 func (eth *EthAPI) Syncing() (common.SyncStatus, error) {
@@ -138,12 +165,10 @@ func (eth *EthAPI) Syncing() (common.SyncStatus, error) {
 	default:
 		var err error
 		var resultBlob []byte
-		r := common.SyncStatus{
-			Result: true,
-		}
+		r := &jsonSyncStatus{}
 		if resultBlob, err = json.Marshal(result); err == nil {
-			if err = json.Unmarshal(resultBlob, &r); err == nil {
-				return r, nil
+			if err = json.Unmarshal(resultBlob, r); err == nil {
+				return r.ToSyncStatus(), nil
 			}
 		}
 
@@ -425,7 +450,7 @@ func (eth *EthAPI) GetCode(address common.Address, quantity string) ([]byte, err
 		return nil, resp.Error()
 	}
 
-	return common.HexToBytes(resp.Get("result").(string)), nil
+	return common.DecodeHex(resp.Get("result").(string))
 }
 
 // Sign signs data with a given address.
@@ -441,7 +466,7 @@ func (eth *EthAPI) Sign(address common.Address, data []byte) ([]byte, error) {
 		return nil, resp.Error()
 	}
 
-	return common.HexToBytes(resp.Get("result").(string)), nil
+	return common.DecodeHex(resp.Get("result").(string))
 }
 
 // SendTransaction creates new message call transaction or a contract creation,
@@ -492,7 +517,7 @@ func (eth *EthAPI) Call(tx *common.TransactionRequest, quantity string) ([]byte,
 		return nil, resp.Error()
 	}
 
-	return common.HexToBytes(resp.Get("result").(string)), nil
+	return common.DecodeHex(resp.Get("result").(string))
 }
 
 // EstimateGas makes a call or transaction, which won't be added to the
@@ -727,74 +752,82 @@ func (eth *EthAPI) GetCompilers() (result []string, err error) {
 
 // NewFilter creates a filter object, based on filter options, to notify when
 // the state changes (logs). To check if the state has changed, call
-// eth_getFilterChanges.
+// eth_getFilterChanges. Invalid options are rejected before they are sent.
 func (eth *EthAPI) NewFilter(option *FilterOption) (Filter, error) {
-	req := eth.requestManager.newRequest("eth_newFilter")
 	if option == nil {
 		option = &FilterOption{}
 	}
-	req.Set("params", option)
-	resp, err := eth.requestManager.send(req)
+	id, err := eth.installFilter(TypeNormal, option)
 	if err != nil {
 		return nil, err
 	}
-
-	if resp.Error() != nil {
-		return nil, resp.Error()
-	}
-
-	id, err := strconv.ParseUint(common.HexToString(resp.Get("result").(string)), 16, 64)
-	if err != nil {
-		return nil, err
+	filter := newFilter(eth, TypeNormal, id, option)
+	// The head block starts the backfill if the filter is lost before it
+	// delivers logs. Without it, such a filter is installed again without
+	// backfill.
+	if head, err := eth.BlockNumber(); err == nil {
+		filter.(*baseFilter).headBlock = head
 	}
-	return newFilter(eth, TypeNormal, id), nil
+	return eth.trackFilter(filter), nil
 }
 
 // NewBlockFilter creates a filter in the node, to notify when a new block
 // arrives. To check if the state has changed, call eth_getFilterChanges.
 func (eth *EthAPI) NewBlockFilter() (Filter, error) {
-	req := eth.requestManager.newRequest("eth_newBlockFilter")
-	resp, err := eth.requestManager.send(req)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.Error() != nil {
-		return nil, resp.Error()
-	}
-
-	id, err := strconv.ParseUint(common.HexToString(resp.Get("result").(string)), 16, 64)
+	id, err := eth.installFilter(TypeBlockFilter, nil)
 	if err != nil {
 		return nil, err
 	}
-	return newFilter(eth, TypeBlockFilter, id), nil
+	return eth.trackFilter(newFilter(eth, TypeBlockFilter, id, nil)), nil
 }
 
 // NewPendingTransactionFilter creates a filter in the node, to notify when new
 // pending transactions arrive. To check if the state has changed, call
 // eth_getFilterChanges.
 func (eth *EthAPI) NewPendingTransactionFilter() (Filter, error) {
-	req := eth.requestManager.newRequest("eth_newPendingTransactionFilter")
-	resp, err := eth.requestManager.send(req)
+	id, err := eth.installFilter(TypeTransactionFilter, nil)
 	if err != nil {
 		return nil, err
 	}
+	return eth.trackFilter(newFilter(eth, TypeTransactionFilter, id, nil)), nil
+}
 
-	if resp.Error() != nil {
-		return nil, resp.Error()
+// installFilter installs a filter in the node and returns its id.
+func (eth *EthAPI) installFilter(filterType FilterType, option *FilterOption) (uint64, error) {
+	var req rpc.Request
+	switch filterType {
+	case TypeBlockFilter:
+		req = eth.requestManager.newRequest("eth_newBlockFilter")
+	case TypeTransactionFilter:
+		req = eth.requestManager.newRequest("eth_newPendingTransactionFilter")
+	default:
+		if err := option.Validate(); err != nil {
+			return 0, err
+		}
+		req = eth.requestManager.newRequest("eth_newFilter")
+		req.Set("params", option)
 	}
-
-	id, err := strconv.ParseUint(common.HexToString(resp.Get("result").(string)), 16, 64)
+	resp, err := eth.requestManager.send(req)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+
+	if resp.Error() != nil {
+		return 0, resp.Error()
 	}
-	return newFilter(eth, TypeTransactionFilter, id), nil
+
+	return strconv.ParseUint(common.HexToString(resp.Get("result").(string)), 16, 64)
 }
 
 // UninstallFilter uninstalls a filter with given id. Should always be called
 // when watch is no longer needed. Additonally Filters timeout when they aren't
 // requested with eth_getFilterChanges for a period of time.
 func (eth *EthAPI) UninstallFilter(filter Filter) (bool, error) {
+	if f, ok := filter.(*baseFilter); ok {
+		f.stopWatching()
+		eth.requestManager.stopPolling(f)
+	}
+
 	req := eth.requestManager.newRequest("eth_uninstallFilter")
 	req.Set("params", fmt.Sprintf("0x%x", filter.ID()))
 	resp, err := eth.requestManager.send(req)
@@ -809,6 +842,13 @@ func (eth *EthAPI) UninstallFilter(filter Filter) (bool, error) {
 	return resp.Get("result").(bool), nil
 }
 
+func (eth *EthAPI) trackFilter(filter Filter) Filter {
+	if f, ok := filter.(*baseFilter); ok {
+		eth.requestManager.startPolling(f)
+	}
+	return filter
+}
+
 // GetFilterChanges polling method for a filter, which returns an array of logs
 // which occurred since last poll.
 func (eth *EthAPI) GetFilterChanges(filter Filter) (result []interface{}, err error) {
@@ -843,9 +883,24 @@ func (eth *EthAPI) GetFilterLogs(filter Filter) (result []interface{}, err error
 }
 
 // GetLogs returns an array of all logs matching a given filter object.
-func (eth *EthAPI) GetLogs(filter Filter) (result []interface{}, err error) {
+func (eth *EthAPI) GetLogs(option *FilterOption) ([]common.Log, error) {
+	if option == nil {
+		option = &FilterOption{}
+	}
+	results, err := eth.getLogs(option)
+	if err != nil {
+		return nil, err
+	}
+	return toLogs(results)
+}
+
+// getLogs returns the raw logs matching a filter object.
+func (eth *EthAPI) getLogs(option *FilterOption) (result []interface{}, err error) {
+	if err := option.Validate(); err != nil {
+		return nil, err
+	}
 	req := eth.requestManager.newRequest("eth_getLogs")
-	req.Set("params", fmt.Sprintf("0x%x", filter.ID()))
+	req.Set("params", option)
 	resp, err := eth.requestManager.send(req)
 	if err != nil {
 		return nil, err
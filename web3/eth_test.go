@@ -30,7 +30,6 @@
 package web3
 
 import (
-	"encoding/json"
 	"math/big"
 	"strings"
 	"testing"
@@ -54,6 +53,13 @@ func (suite *EthTestSuite) Test_ProcotolVersion() {
 	assert.NotEqual(suite.T(), "", result, "version is empty")
 }
 
+func (suite *EthTestSuite) Test_ChainID() {
+	eth := suite.eth
+	chainID, err := eth.ChainID()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), big.NewInt(1), chainID, "Should be equal")
+}
+
 func (suite *EthTestSuite) Test_Syncing() {
 	eth := suite.eth
 	status, err := eth.Syncing()
@@ -524,14 +530,9 @@ func (suite *EthTestSuite) Test_GetFilterChanges() {
 	}
 	returnedLogs, err := eth.GetFilterChanges(filter)
 	if assert.NoError(suite.T(), err, "Should be no error") {
-		for i, l := range returnedLogs {
-			log := common.Log{}
-			rawBytes, err := json.Marshal(l)
-			assert.NoError(suite.T(), err, "Should be no error")
-			err = json.Unmarshal(rawBytes, &log)
-			assert.NoError(suite.T(), err, "Should be no error")
-			assert.EqualValues(suite.T(), logs[i], log, "Should be equal")
-		}
+		decoded, err := toLogs(returnedLogs)
+		assert.NoError(suite.T(), err, "Should be no error")
+		assert.EqualValues(suite.T(), logs, decoded, "Should be equal")
 	}
 }
 
@@ -557,21 +558,15 @@ func (suite *EthTestSuite) Test_GetFilterLogs() {
 	}
 	returnedLogs, err := eth.GetFilterLogs(filter)
 	if assert.NoError(suite.T(), err, "Should be no error") {
-		for i, l := range returnedLogs {
-			log := common.Log{}
-			rawBytes, err := json.Marshal(l)
-			assert.NoError(suite.T(), err, "Should be no error")
-			err = json.Unmarshal(rawBytes, &log)
-			assert.NoError(suite.T(), err, "Should be no error")
-			assert.EqualValues(suite.T(), logs[i], log, "Should be equal")
-		}
+		decoded, err := toLogs(returnedLogs)
+		assert.NoError(suite.T(), err, "Should be no error")
+		assert.EqualValues(suite.T(), logs, decoded, "Should be equal")
 	}
 }
 
 func (suite *EthTestSuite) Test_GetLogs() {
 	eth := suite.eth
-	option := &FilterOption{}
-	filter, err := eth.NewFilter(option)
+	option := &FilterOption{FromBlock: "0x1", ToBlock: "latest"}
 	logs := []common.Log{
 		{
 			LogIndex:         0x1,
@@ -588,16 +583,9 @@ func (suite *EthTestSuite) Test_GetLogs() {
 			},
 		},
 	}
-	returnedLogs, err := eth.GetLogs(filter)
+	returnedLogs, err := eth.GetLogs(option)
 	if assert.NoError(suite.T(), err, "Should be no error") {
-		for i, l := range returnedLogs {
-			log := common.Log{}
-			rawBytes, err := json.Marshal(l)
-			assert.NoError(suite.T(), err, "Should be no error")
-			err = json.Unmarshal(rawBytes, &log)
-			assert.NoError(suite.T(), err, "Should be no error")
-			assert.EqualValues(suite.T(), logs[i], log, "Should be equal")
-		}
+		assert.EqualValues(suite.T(), logs, returnedLogs, "Should be equal")
 	}
 }
 
@@ -31,22 +31,13 @@ package web3
 
 import (
 	"encoding/json"
-	"errors"
+	"math/big"
+	"strings"
 	"sync"
-	"time"
 
 	"github.com/alanchchen/web3go/common"
 )
 
-var (
-	ErrChannelClosed = errors.New("Channel is closed")
-)
-
-const (
-	pollInterval   = 100 * time.Millisecond
-	dataBufferSize = 16
-)
-
 type FilterType int
 
 const (
@@ -55,12 +46,16 @@ const (
 	TypeTransactionFilter
 )
 
-// FilterOption ...
+// FilterOption holds the criteria of a log filter, see FilterBuilder.
+// Address is a hex string, a common.Address or a slice of either.
 type FilterOption struct {
-	FromBlock string        `json:"fromBlock,omitempty"`
-	ToBlock   string        `json:"toBlock,omitempty"`
-	Address   interface{}   `json:"address,omitempty"`
-	Topics    common.Topics `json:"topics,omitempty"`
+	FromBlock string      `json:"fromBlock,omitempty"`
+	ToBlock   string      `json:"toBlock,omitempty"`
+	Address   interface{} `json:"address,omitempty"`
+	Topics    TopicFilter `json:"topics,omitempty"`
+	// BlockHash restricts eth_getLogs to a single block (EIP-234), FromBlock
+	// and ToBlock must be empty then.
+	BlockHash string `json:"blockHash,omitempty"`
 }
 
 func (opt *FilterOption) String() string {
@@ -71,86 +66,270 @@ func (opt *FilterOption) String() string {
 // Filter ...
 type Filter interface {
 	Watch() WatchChannel
+	WatchWithOption(option *WatchOption) WatchChannel
 	ID() uint64
 }
 
 type baseFilter struct {
-	eth        Eth
+	eth        *EthAPI
 	filterType FilterType
-	filterID   uint64
-}
+	option     *FilterOption
+	watchers   watcherSet
 
-// WatchChannel ...
-type WatchChannel interface {
-	Next() (interface{}, error)
-	Close()
-}
+	// reinstallLock serializes reinstallation between watchers.
+	reinstallLock sync.Mutex
 
-type watchChannel struct {
-	dataCh  chan interface{}
-	closeCh chan struct{}
+	lock       sync.Mutex
+	filterID   uint64
+	generation uint64
+	// Position of the last log delivered, to drop logs delivered already.
+	lastBlock    *big.Int
+	lastLogIndex *big.Int
+	// headBlock is the head block after the installation or the last
+	// backfill of a log filter. Missed logs are backfilled from there, or
+	// from the last log delivered if later, after reinstallation.
+	headBlock *big.Int
+	// backfillPending is set when the filter was reinstalled but the missed
+	// logs are not fetched yet.
+	backfillPending bool
+	// Logs up to skipThrough were backfilled, the reinstalled filter may
+	// report them again. It is cleared by the first poll after the backfill,
+	// later changes are for newer blocks.
+	skipThrough *big.Int
 }
 
 // -----------------------------------------------------------------------------
 // Filter
 
 // newFilter creates a filter object, based on filter options and filter id.
-func newFilter(eth Eth, filterType FilterType, id uint64) Filter {
+func newFilter(eth *EthAPI, filterType FilterType, id uint64, option *FilterOption) Filter {
+	if option != nil {
+		copied := *option
+		option = &copied
+	}
 	return &baseFilter{
 		eth:        eth,
 		filterType: filterType,
 		filterID:   id,
+		option:     option,
 	}
 }
 
+// Watch polls the filter for changes with the default WatchOption.
 func (f *baseFilter) Watch() WatchChannel {
-	dataCh := make(chan interface{}, dataBufferSize)
-	closeCh := make(chan struct{})
-	var wg sync.WaitGroup
-	wg.Add(1)
-
-	go func(wg *sync.WaitGroup, closeCh <-chan struct{}, dataCh chan<- interface{}) {
-		// TODO: configurable timer
-		ticker := time.NewTicker(pollInterval)
-		defer ticker.Stop()
-
-		wg.Done()
-
-		for {
-			select {
-			case <-closeCh:
-				close(dataCh)
-				return
-			case <-ticker.C:
-				results, _ := f.eth.GetFilterChanges(f)
-				for _, r := range results {
-					dataCh <- r
-				}
-			}
-		}
-	}(&wg, closeCh, dataCh)
+	return f.WatchWithOption(nil)
+}
 
-	return &watchChannel{
-		dataCh:  dataCh,
-		closeCh: closeCh,
-	}
+// WatchWithOption polls the filter for changes. Poll errors are reported
+// through WatchChannel.Next, polling goes on until the channel is closed.
+//
+// When the node forgets the filter, because it was not polled for a while or
+// the node restarted, the filter is installed again. Log filters then fetch
+// the logs missed in between with eth_getLogs, starting from the block of
+// the last log delivered, or from the head block of the installation.
+func (f *baseFilter) WatchWithOption(option *WatchOption) WatchChannel {
+	wc := newWatchChannel(option)
+	f.watchers.add(wc)
+	go wc.run(f.poll)
+	return wc
 }
 
 // ID returns the filter identifier
 func (f *baseFilter) ID() uint64 {
+	f.lock.Lock()
+	defer f.lock.Unlock()
 	return f.filterID
 }
 
-// -----------------------------------------------------------------------------
-// WatchChannel
+func (f *baseFilter) poll() ([]interface{}, error) {
+	f.lock.Lock()
+	generation := f.generation
+	backfillPending := f.backfillPending
+	skipThrough := f.skipThrough
+	f.lock.Unlock()
+
+	if backfillPending {
+		return f.backfill()
+	}
+
+	results, err := f.eth.GetFilterChanges(f)
+	if err != nil {
+		if isFilterNotFound(err) {
+			return f.reinstall(generation)
+		}
+		return nil, err
+	}
+
+	results = f.track(results, false)
+
+	f.lock.Lock()
+	if skipThrough != nil && f.skipThrough == skipThrough {
+		// The changes of the backfilled range were drained.
+		f.skipThrough = nil
+	}
+	f.lock.Unlock()
+	return results, nil
+}
+
+// advance moves headBlock forward to head. The caller must hold lock.
+func (f *baseFilter) advance(head *big.Int) {
+	if head != nil && (f.headBlock == nil || head.Cmp(f.headBlock) > 0) {
+		f.headBlock = head
+	}
+}
+
+// reinstall installs the filter again, unless another watcher already did
+// since the given generation.
+func (f *baseFilter) reinstall(generation uint64) ([]interface{}, error) {
+	f.reinstallLock.Lock()
+	defer f.reinstallLock.Unlock()
+
+	f.lock.Lock()
+	reinstalled := f.generation != generation
+	f.lock.Unlock()
+	if reinstalled {
+		return nil, nil
+	}
+
+	id, err := f.eth.installFilter(f.filterType, f.option)
+	if err != nil {
+		return nil, err
+	}
+
+	f.lock.Lock()
+	f.filterID = id
+	f.generation++
+	f.backfillPending = f.filterType == TypeNormal && (f.headBlock != nil || f.lastBlock != nil)
+	backfillPending := f.backfillPending
+	f.lock.Unlock()
+
+	if backfillPending {
+		return f.backfill()
+	}
+	return nil, nil
+}
+
+// backfill fetches the logs missed while the filter was not installed, from
+// the block of the last log delivered, or the head block of the installation
+// or last backfill if later. Logs of that block delivered already are
+// dropped by track. A "safe" or "finalized" ToBlock bounds the range at the
+// tagged block.
+func (f *baseFilter) backfill() ([]interface{}, error) {
+	latest, err := f.eth.BlockNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	f.lock.Lock()
+	fromBlock := f.headBlock
+	if f.lastBlock != nil && (fromBlock == nil || f.lastBlock.Cmp(fromBlock) > 0) {
+		fromBlock = f.lastBlock
+	}
+	f.lock.Unlock()
+
+	option := *f.option
+	if from, err := common.DecodeQuantity(option.FromBlock); err == nil && from.Cmp(fromBlock) > 0 {
+		fromBlock = from
+	}
+	toBlock := latest
+	to, err := common.DecodeQuantity(option.ToBlock)
+	if option.ToBlock == "safe" || option.ToBlock == "finalized" {
+		if to, err = f.eth.taggedBlockNumber(option.ToBlock); err != nil {
+			return nil, err
+		}
+	}
+	if err == nil && to.Cmp(latest) < 0 {
+		toBlock = to
+	}
+
+	var logs []interface{}
+	if fromBlock.Cmp(toBlock) <= 0 {
+		option.FromBlock = common.EncodeQuantity(fromBlock)
+		option.ToBlock = common.EncodeQuantity(toBlock)
+		if logs, err = f.eth.getLogs(&option); err != nil {
+			return nil, err
+		}
+	}
+
+	f.lock.Lock()
+	f.backfillPending = false
+	f.skipThrough = toBlock
+	f.advance(toBlock)
+	f.lock.Unlock()
+	return f.track(logs, true), nil
+}
+
+// track records the position of delivered logs, and drops logs delivered
+// already: backfilled logs up to the last log seen, and changes of a
+// reinstalled filter that were backfilled.
+func (f *baseFilter) track(results []interface{}, backfilled bool) []interface{} {
+	if f.filterType != TypeNormal {
+		return results
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
 
-func (wc *watchChannel) Next() (interface{}, error) {
-	if data, ok := <-wc.dataCh; ok {
-		return data, nil
+	tracked := results[:0]
+	for _, r := range results {
+		log, _ := r.(map[string]interface{})
+		blockNumber := rawQuantity(log["blockNumber"])
+		logIndex := rawQuantity(log["logIndex"])
+		removed, _ := log["removed"].(bool)
+		if blockNumber == nil || logIndex == nil {
+			tracked = append(tracked, r)
+			continue
+		}
+
+		if backfilled && f.lastBlock != nil {
+			if c := blockNumber.Cmp(f.lastBlock); c < 0 || c == 0 && logIndex.Cmp(f.lastLogIndex) <= 0 {
+				continue
+			}
+		}
+		if !backfilled && !removed && f.skipThrough != nil && blockNumber.Cmp(f.skipThrough) <= 0 {
+			continue
+		}
+
+		if !removed {
+			f.lastBlock = blockNumber
+			f.lastLogIndex = logIndex
+		}
+		tracked = append(tracked, r)
 	}
-	return nil, ErrChannelClosed
+	return tracked
+}
+
+// stop closes all watch channels and uninstalls the filter from the node.
+func (f *baseFilter) stop() {
+	f.eth.UninstallFilter(f)
 }
 
-func (wc *watchChannel) Close() {
-	wc.closeCh <- struct{}{}
+func (f *baseFilter) syncing() bool {
+	return false
+}
+
+func (f *baseFilter) stopWatching() {
+	f.watchers.closeAll()
+}
+
+func isFilterNotFound(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "filter not found")
+}
+
+// rawQuantity reads a quantity from a decoded JSON value, either a hex string
+// as sent by nodes or a plain number.
+func rawQuantity(value interface{}) *big.Int {
+	switch v := value.(type) {
+	case string:
+		if result, ok := new(big.Int).SetString(common.HexToString(v), 16); ok {
+			return result
+		}
+	case float64:
+		return big.NewInt(int64(v))
+	case json.Number:
+		if result, ok := new(big.Int).SetString(v.String(), 10); ok {
+			return result
+		}
+	}
+	return nil
 }
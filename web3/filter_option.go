@@ -0,0 +1,251 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/alanchchen/web3go/common"
+)
+
+var (
+	ErrInvalidBlockTag    = errors.New("Invalid block number or tag")
+	ErrBlockHashWithRange = errors.New("Block hash filters can't have a block range")
+	ErrInvalidAddressType = errors.New("Invalid filter address type")
+	ErrTooManyTopics      = errors.New("Too many topic positions")
+)
+
+// maxTopics is the number of indexed topics a log can have.
+const maxTopics = 4
+
+// TopicFilter selects logs by topic position. Each position holds the
+// accepted topics, an empty position matches any topic.
+type TopicFilter [][]common.Hash
+
+// MarshalJSON encodes wildcards as null, and positions with a single topic as
+// a plain hash.
+func (t TopicFilter) MarshalJSON() ([]byte, error) {
+	positions := make([]interface{}, len(t))
+	for i, alternatives := range t {
+		switch len(alternatives) {
+		case 0:
+		case 1:
+			positions[i] = alternatives[0].String()
+		default:
+			hashes := make([]string, len(alternatives))
+			for j := range alternatives {
+				hashes[j] = alternatives[j].String()
+			}
+			positions[i] = hashes
+		}
+	}
+	return json.Marshal(positions)
+}
+
+// MarshalJSON encodes addresses as hex strings.
+func (opt FilterOption) MarshalJSON() ([]byte, error) {
+	type jsonFilterOption FilterOption
+	raw := jsonFilterOption(opt)
+	switch address := opt.Address.(type) {
+	case common.Address:
+		raw.Address = address.String()
+	case []common.Address:
+		addresses := make([]string, len(address))
+		for i := range address {
+			addresses[i] = address[i].String()
+		}
+		raw.Address = addresses
+	}
+	return json.Marshal(raw)
+}
+
+// Addresses returns the addresses of the filter, nil if any address matches.
+func (opt *FilterOption) Addresses() ([]common.Address, error) {
+	switch address := opt.Address.(type) {
+	case nil:
+		return nil, nil
+	case common.Address:
+		return []common.Address{address}, nil
+	case []common.Address:
+		return address, nil
+	case string:
+		addr, err := common.ParseAddress(address)
+		if err != nil {
+			return nil, err
+		}
+		return []common.Address{addr}, nil
+	case []string:
+		addresses := make([]common.Address, len(address))
+		for i, s := range address {
+			addr, err := common.ParseAddress(s)
+			if err != nil {
+				return nil, err
+			}
+			addresses[i] = addr
+		}
+		return addresses, nil
+	}
+	return nil, ErrInvalidAddressType
+}
+
+// Validate checks the criteria before they are sent to the node.
+func (opt *FilterOption) Validate() error {
+	if opt.BlockHash != "" {
+		if opt.FromBlock != "" || opt.ToBlock != "" {
+			return ErrBlockHashWithRange
+		}
+		if _, err := common.ParseHash(opt.BlockHash); err != nil {
+			return err
+		}
+	}
+
+	from, err := checkBlockTag(opt.FromBlock)
+	if err != nil {
+		return err
+	}
+	to, err := checkBlockTag(opt.ToBlock)
+	if err != nil {
+		return err
+	}
+	if from >= 0 && to >= 0 && from > to {
+		return ErrInvalidBlockRange
+	}
+
+	if _, err := opt.Addresses(); err != nil {
+		return err
+	}
+	if len(opt.Topics) > maxTopics {
+		return ErrTooManyTopics
+	}
+	return nil
+}
+
+// checkBlockTag returns the block number of a quantity, or -1 for a tag.
+func checkBlockTag(block string) (int64, error) {
+	switch block {
+	case "", "earliest", "latest", "pending", "safe", "finalized":
+		return -1, nil
+	}
+	number, err := common.DecodeQuantity(block)
+	if err != nil || !number.IsInt64() {
+		return 0, ErrInvalidBlockTag
+	}
+	return number.Int64(), nil
+}
+
+// FilterBuilder builds the criteria of a log filter. Errors are reported by
+// Build.
+//
+//	option, err := NewFilterBuilder().
+//		FromBlock("0x1").
+//		Address(token).
+//		Topic(0, transferTopic).
+//		Topic(2, fromTopic, toTopic).
+//		Build()
+type FilterBuilder struct {
+	option    FilterOption
+	addresses []common.Address
+	err       error
+}
+
+// NewFilterBuilder creates a builder matching every log of the latest block.
+func NewFilterBuilder() *FilterBuilder {
+	return &FilterBuilder{}
+}
+
+// FromBlock sets the first block, a quantity or a tag like "earliest".
+func (b *FilterBuilder) FromBlock(block string) *FilterBuilder {
+	b.option.FromBlock = block
+	return b
+}
+
+// ToBlock sets the last block, a quantity or a tag like "latest".
+func (b *FilterBuilder) ToBlock(block string) *FilterBuilder {
+	b.option.ToBlock = block
+	return b
+}
+
+// BlockRange sets the first and last block numbers.
+func (b *FilterBuilder) BlockRange(from, to uint64) *FilterBuilder {
+	b.option.FromBlock = common.EncodeUint64Quantity(from)
+	b.option.ToBlock = common.EncodeUint64Quantity(to)
+	return b
+}
+
+// BlockHash restricts the filter to a single block (EIP-234). It can't be
+// combined with a block range.
+func (b *FilterBuilder) BlockHash(hash common.Hash) *FilterBuilder {
+	b.option.BlockHash = hash.String()
+	return b
+}
+
+// Address adds addresses, logs emitted by any of them match.
+func (b *FilterBuilder) Address(addresses ...common.Address) *FilterBuilder {
+	b.addresses = append(b.addresses, addresses...)
+	return b
+}
+
+// Topic sets the accepted topics at a position, from 0 to 3. Without topics
+// the position matches any topic.
+func (b *FilterBuilder) Topic(position int, topics ...common.Hash) *FilterBuilder {
+	if position < 0 || position >= maxTopics {
+		if b.err == nil {
+			b.err = ErrTooManyTopics
+		}
+		return b
+	}
+	for len(b.option.Topics) <= position {
+		b.option.Topics = append(b.option.Topics, nil)
+	}
+	b.option.Topics[position] = append([]common.Hash(nil), topics...)
+	return b
+}
+
+// Build validates the criteria and returns them.
+func (b *FilterBuilder) Build() (*FilterOption, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	option := b.option
+	option.Topics = append(TopicFilter(nil), b.option.Topics...)
+	switch len(b.addresses) {
+	case 0:
+	case 1:
+		option.Address = b.addresses[0]
+	default:
+		option.Address = append([]common.Address(nil), b.addresses...)
+	}
+	if err := option.Validate(); err != nil {
+		return nil, err
+	}
+	return &option, nil
+}
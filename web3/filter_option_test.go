@@ -0,0 +1,136 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/alanchchen/web3go/common"
+	"github.com/alanchchen/web3go/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type FilterOptionTestSuite struct {
+	suite.Suite
+}
+
+var (
+	testAddress1 = common.NewAddress(common.HexToBytes("0x16c5785ac562ff41e2dcfdf829c5a142f1fccd7d"))
+	testAddress2 = common.NewAddress(common.HexToBytes("0x407d73d8a49eeb85d32cf465507dd71d507100c1"))
+	testTopic1   = common.NewHash(common.HexToBytes("0x59ebeb90bc63057b6515673c3ecf9438e5058bca0f92585014eced636878c9a5"))
+	testTopic2   = common.NewHash(common.HexToBytes("0xc6ef2fc5426d6ad6fd9e2a26abeab0aa2411b7ab17f30a99d3cb96aed1d1055b"))
+)
+
+func (suite *FilterOptionTestSuite) Test_Build() {
+	option, err := NewFilterBuilder().
+		BlockRange(1, 0x1b4).
+		Address(testAddress1, testAddress2).
+		Topic(0, testTopic1).
+		Topic(2, testTopic1, testTopic2).
+		Build()
+	assert.NoError(suite.T(), err, "Should be no error")
+
+	expected := `{"fromBlock":"0x1","toBlock":"0x1b4",` +
+		`"address":["0x16c5785ac562ff41e2dcfdf829c5a142f1fccd7d","0x407d73d8a49eeb85d32cf465507dd71d507100c1"],` +
+		`"topics":["0x59ebeb90bc63057b6515673c3ecf9438e5058bca0f92585014eced636878c9a5",null,` +
+		`["0x59ebeb90bc63057b6515673c3ecf9438e5058bca0f92585014eced636878c9a5","0xc6ef2fc5426d6ad6fd9e2a26abeab0aa2411b7ab17f30a99d3cb96aed1d1055b"]]}`
+	assert.Equal(suite.T(), expected, option.String(), "Should be equal")
+}
+
+func (suite *FilterOptionTestSuite) Test_BuildBlockHash() {
+	option, err := NewFilterBuilder().BlockHash(testTopic2).Address(testAddress1).Build()
+	assert.NoError(suite.T(), err, "Should be no error")
+	expected := `{"address":"0x16c5785ac562ff41e2dcfdf829c5a142f1fccd7d",` +
+		`"blockHash":"0xc6ef2fc5426d6ad6fd9e2a26abeab0aa2411b7ab17f30a99d3cb96aed1d1055b"}`
+	assert.Equal(suite.T(), expected, option.String(), "Should be equal")
+
+	_, err = NewFilterBuilder().BlockHash(testTopic2).FromBlock("latest").Build()
+	assert.Equal(suite.T(), ErrBlockHashWithRange, err, "Should be equal")
+}
+
+func (suite *FilterOptionTestSuite) Test_BuildErrors() {
+	_, err := NewFilterBuilder().Topic(4, testTopic1).Build()
+	assert.Equal(suite.T(), ErrTooManyTopics, err, "Should be equal")
+	_, err = NewFilterBuilder().Topic(-1).Build()
+	assert.Equal(suite.T(), ErrTooManyTopics, err, "Should be equal")
+	_, err = NewFilterBuilder().BlockRange(10, 1).Build()
+	assert.Equal(suite.T(), ErrInvalidBlockRange, err, "Should be equal")
+	_, err = NewFilterBuilder().FromBlock("head").Build()
+	assert.Equal(suite.T(), ErrInvalidBlockTag, err, "Should be equal")
+	_, err = NewFilterBuilder().FromBlock("0x01").Build()
+	assert.Equal(suite.T(), ErrInvalidBlockTag, err, "Should be equal")
+}
+
+func (suite *FilterOptionTestSuite) Test_Validate() {
+	tests := []struct {
+		option *FilterOption
+		err    error
+	}{
+		{&FilterOption{}, nil},
+		{&FilterOption{FromBlock: "earliest", ToBlock: "finalized"}, nil},
+		{&FilterOption{Address: "0x16c5785ac562ff41e2dcfdf829c5a142f1fccd7d"}, nil},
+		{&FilterOption{Address: []string{"0x16c5785ac562ff41e2dcfdf829c5a142f1fccd7d"}}, nil},
+		{&FilterOption{Address: "0x16c5785ac562ff41e2dcfdf829c5a142f1fccd"}, common.ErrInvalidAddressLength},
+		{&FilterOption{Address: 42}, ErrInvalidAddressType},
+		{&FilterOption{BlockHash: "0x1b4"}, common.ErrInvalidHashLength},
+		{&FilterOption{Topics: make(TopicFilter, 5)}, ErrTooManyTopics},
+	}
+	for _, test := range tests {
+		assert.Equal(suite.T(), test.err, test.option.Validate(), "Should be equal")
+	}
+}
+
+func (suite *FilterOptionTestSuite) Test_Addresses() {
+	option := &FilterOption{Address: []string{
+		"0x16c5785ac562ff41e2dcfdf829c5a142f1fccd7d",
+		"0x407d73d8a49eeb85d32cf465507dd71d507100c1",
+	}}
+	addresses, err := option.Addresses()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), []common.Address{testAddress1, testAddress2}, addresses, "Should be equal")
+
+	raw, err := json.Marshal(&FilterOption{Address: testAddress1})
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), `{"address":"0x16c5785ac562ff41e2dcfdf829c5a142f1fccd7d"}`, string(raw), "Should be equal")
+}
+
+func (suite *FilterOptionTestSuite) Test_NewFilterValidates() {
+	eth := NewWeb3(test.NewMockHTTPProvider()).Eth
+	_, err := eth.NewFilter(&FilterOption{FromBlock: "0x2", ToBlock: "0x1"})
+	assert.Equal(suite.T(), ErrInvalidBlockRange, err, "Should be equal")
+	_, err = eth.GetLogs(&FilterOption{Address: 42})
+	assert.Equal(suite.T(), ErrInvalidAddressType, err, "Should be equal")
+}
+
+func Test_FilterOptionTestSuite(t *testing.T) {
+	suite.Run(t, new(FilterOptionTestSuite))
+}
@@ -0,0 +1,239 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/alanchchen/web3go/provider"
+	"github.com/alanchchen/web3go/rpc"
+	"github.com/alanchchen/web3go/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// forgetfulProvider reports unknown filters while lost is set, like a node
+// that dropped its filters.
+type forgetfulProvider struct {
+	provider.Provider
+
+	lock    sync.Mutex
+	lost    bool
+	head    string
+	calls   map[string]int
+	getLogs []*FilterOption
+}
+
+func (p *forgetfulProvider) Send(request rpc.Request) (rpc.Response, error) {
+	method := request.Get("method").(string)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.calls[method]++
+	switch method {
+	case "eth_getFilterChanges":
+		if p.lost {
+			raw := fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"error":{"code":-32000,"message":"filter not found"}}`, request.ID())
+			return p.GetRPCMethod().NewResponse([]byte(raw)), nil
+		}
+	case "eth_blockNumber":
+		if p.head != "" {
+			raw := fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":"%s"}`, request.ID(), p.head)
+			return p.GetRPCMethod().NewResponse([]byte(raw)), nil
+		}
+	case "eth_newFilter", "eth_newBlockFilter":
+		p.lost = false
+	case "eth_getLogs":
+		p.getLogs = append(p.getLogs, request.Get("params").([]interface{})[0].(*FilterOption))
+	}
+	return p.Provider.Send(request)
+}
+
+func (p *forgetfulProvider) setHead(head string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.head = head
+}
+
+func (p *forgetfulProvider) forget() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.lost = true
+}
+
+type FilterTestSuite struct {
+	suite.Suite
+	provider *forgetfulProvider
+	web3     *Web3
+}
+
+func (suite *FilterTestSuite) Test_ReinstallLogFilter() {
+	filter, err := suite.web3.Eth.NewFilter(&FilterOption{ToBlock: "latest"})
+	assert.NoError(suite.T(), err, "Should be no error")
+	f := filter.(*baseFilter)
+
+	logs, err := f.poll()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Len(suite.T(), logs, 1, "Should be equal")
+
+	suite.provider.forget()
+	logs, err = f.poll()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Empty(suite.T(), logs, "Logs seen already should be dropped")
+	assert.Equal(suite.T(), 2, suite.provider.calls["eth_newFilter"], "Should be equal")
+	if assert.Len(suite.T(), suite.provider.getLogs, 1, "Should be equal") {
+		assert.Equal(suite.T(), "0x4b7", suite.provider.getLogs[0].FromBlock, "Should start at the head of the installation")
+		assert.Equal(suite.T(), "0x4b7", suite.provider.getLogs[0].ToBlock, "Should be equal")
+	}
+
+	logs, err = f.poll()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Empty(suite.T(), logs, "Backfilled logs should be dropped")
+
+	logs, err = f.poll()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Len(suite.T(), logs, 1, "Logs emitted again after the backfilled range should be delivered")
+}
+
+func (suite *FilterTestSuite) Test_ReinstallWithoutLogs() {
+	suite.provider.setHead("0x10")
+	filter, err := suite.web3.Eth.NewFilter(nil)
+	assert.NoError(suite.T(), err, "Should be no error")
+	f := filter.(*baseFilter)
+
+	suite.provider.setHead("0x20")
+	suite.provider.forget()
+	_, err = f.poll()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), 2, suite.provider.calls["eth_newFilter"], "Should be equal")
+	if assert.Len(suite.T(), suite.provider.getLogs, 1, "Should backfill from the installation") {
+		assert.Equal(suite.T(), "0x10", suite.provider.getLogs[0].FromBlock, "Should be equal")
+		assert.Equal(suite.T(), "0x20", suite.provider.getLogs[0].ToBlock, "Should be equal")
+	}
+}
+
+func (suite *FilterTestSuite) Test_ReinstallFromLastLog() {
+	suite.provider.setHead("0x10")
+	filter, err := suite.web3.Eth.NewFilter(nil)
+	assert.NoError(suite.T(), err, "Should be no error")
+	f := filter.(*baseFilter)
+
+	logs, err := f.poll()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Len(suite.T(), logs, 1, "Should be equal")
+
+	suite.provider.setHead("0x200")
+	suite.provider.forget()
+	_, err = f.poll()
+	assert.NoError(suite.T(), err, "Should be no error")
+	if assert.Len(suite.T(), suite.provider.getLogs, 1, "Should be equal") {
+		assert.Equal(suite.T(), "0x1b4", suite.provider.getLogs[0].FromBlock, "Should start at the last log")
+		assert.Equal(suite.T(), "0x200", suite.provider.getLogs[0].ToBlock, "Should be equal")
+	}
+}
+
+func (suite *FilterTestSuite) Test_ReinstallToFinalized() {
+	suite.provider.setHead("0x100")
+	filter, err := suite.web3.Eth.NewFilter(&FilterOption{ToBlock: "finalized"})
+	assert.NoError(suite.T(), err, "Should be no error")
+	f := filter.(*baseFilter)
+
+	suite.provider.setHead("0x200")
+	suite.provider.forget()
+	_, err = f.poll()
+	assert.NoError(suite.T(), err, "Should be no error")
+	if assert.Len(suite.T(), suite.provider.getLogs, 1, "Should be equal") {
+		assert.Equal(suite.T(), "0x100", suite.provider.getLogs[0].FromBlock, "Should be equal")
+		assert.Equal(suite.T(), "0x1b4", suite.provider.getLogs[0].ToBlock, "Should stop at the finalized block")
+	}
+}
+
+func (suite *FilterTestSuite) Test_PollTraffic() {
+	_, err := suite.web3.Eth.NewFilter(&FilterOption{BlockHash: "0x1", FromBlock: "0x1"})
+	assert.Error(suite.T(), err, "Should be error")
+	assert.Equal(suite.T(), 0, suite.provider.calls["eth_blockNumber"], "Invalid options should fail first")
+
+	filter, err := suite.web3.Eth.NewFilter(nil)
+	assert.NoError(suite.T(), err, "Should be no error")
+	f := filter.(*baseFilter)
+	for i := 0; i < 3; i++ {
+		_, err = f.poll()
+		assert.NoError(suite.T(), err, "Should be no error")
+	}
+	assert.Equal(suite.T(), 1, suite.provider.calls["eth_blockNumber"], "Polls should not fetch the head")
+}
+
+func (suite *FilterTestSuite) Test_ReinstallBlockFilter() {
+	filter, err := suite.web3.Eth.NewBlockFilter()
+	assert.NoError(suite.T(), err, "Should be no error")
+	f := filter.(*baseFilter)
+
+	suite.provider.forget()
+	_, err = f.poll()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), 2, suite.provider.calls["eth_newBlockFilter"], "Should be equal")
+	assert.Empty(suite.T(), suite.provider.getLogs, "Block filters are not backfilled")
+
+	_, err = f.poll()
+	assert.NoError(suite.T(), err, "Should be no error")
+}
+
+func (suite *FilterTestSuite) Test_PollError() {
+	filter, err := suite.web3.Eth.NewFilter(nil)
+	assert.NoError(suite.T(), err, "Should be no error")
+	f := filter.(*baseFilter)
+
+	suite.web3.SetProvider(&failingProvider{suite.provider})
+	_, err = f.poll()
+	assert.Error(suite.T(), err, "Should be an error")
+	assert.Equal(suite.T(), 1, suite.provider.calls["eth_newFilter"], "Other errors should not reinstall")
+}
+
+type failingProvider struct {
+	provider.Provider
+}
+
+func (p *failingProvider) Send(request rpc.Request) (rpc.Response, error) {
+	return nil, fmt.Errorf("Connection refused")
+}
+
+func (suite *FilterTestSuite) SetupTest() {
+	suite.provider = &forgetfulProvider{
+		Provider: test.NewMockHTTPProvider(),
+		calls:    make(map[string]int),
+	}
+	suite.web3 = NewWeb3(suite.provider)
+}
+
+func Test_FilterTestSuite(t *testing.T) {
+	suite.Run(t, new(FilterTestSuite))
+}
@@ -0,0 +1,412 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/alanchchen/web3go/common"
+	"github.com/alanchchen/web3go/rpc"
+)
+
+var (
+	// ErrReorgTooDeep is returned by a Follower when a chain reorganization
+	// reaches beyond the blocks it remembers. The follower goes on from the
+	// new chain, blocks delivered before are not reported as removed.
+	ErrReorgTooDeep = errors.New("Chain reorganization is deeper than the follower history")
+	// ErrUnknownCheckpoint is returned by Follow when the node doesn't know
+	// the checkpoint block, so the chain can't be checked for reorganizations.
+	ErrUnknownCheckpoint = errors.New("Checkpoint block is unknown")
+)
+
+const followHistory = 128
+
+// FollowOption configures a Follower. Zero fields take defaults.
+type FollowOption struct {
+	// FromBlock is the first block delivered, the current head by default.
+	FromBlock string
+	// Confirmations delays blocks until that many blocks are built on top of
+	// them. Reorganizations shallower than that are never seen.
+	Confirmations uint64
+	// Logs selects the logs delivered with each block, block range fields are
+	// ignored. Logs are not fetched if nil.
+	Logs *FilterOption
+	// History is the number of recent blocks kept to detect reorganizations,
+	// 128 by default. It is raised to Confirmations+1 if lower.
+	History uint64
+	// Watch configures polling and buffering of events.
+	Watch *WatchOption
+	// Checkpoint persists the last block processed. If it holds a checkpoint,
+	// the follower resumes after it and FromBlock is ignored.
+	Checkpoint CheckpointStore
+}
+
+// ChainEvent reports a block added to or removed from the canonical chain.
+type ChainEvent struct {
+	// Removed is set when the block left the canonical chain. Its logs are
+	// flagged as removed as well.
+	Removed bool
+	Block   *common.Block
+	Logs    []common.Log
+}
+
+// Follower delivers chain events in chain order. After a reorganization the
+// replaced blocks are removed, newest first, before the new blocks are added.
+//
+// With a CheckpointStore, an event counts as processed when Next is called
+// again, and the checkpoint is saved then. Events are delivered at least
+// once: the last event before a restart is delivered again.
+type Follower interface {
+	Next() (*ChainEvent, error)
+	Close()
+}
+
+type followedBlock struct {
+	block   *common.Block
+	logs    []common.Log
+	emitted bool
+}
+
+type follower struct {
+	eth      *EthAPI
+	option   FollowOption
+	filter   *baseFilter
+	wc       *watchChannel
+	stopOnce sync.Once
+
+	// pending is the last event returned by Next, not checkpointed yet.
+	pending *ChainEvent
+
+	// The fields below are only used by the polling goroutine.
+	start  *big.Int
+	next   *big.Int
+	blocks []*followedBlock
+	behind bool
+}
+
+// Follow walks the chain from FromBlock and reports added and removed blocks.
+// New blocks are detected with a block filter, or by polling eth_blockNumber
+// if the node does not support filters.
+func (eth *EthAPI) Follow(option *FollowOption) (Follower, error) {
+	opt := FollowOption{}
+	if option != nil {
+		opt = *option
+	}
+	if opt.History == 0 {
+		opt.History = followHistory
+	}
+	if opt.History <= opt.Confirmations {
+		opt.History = opt.Confirmations + 1
+	}
+
+	// The first poll walks to the head without waiting for a new block.
+	f := &follower{eth: eth, option: opt, behind: true}
+	var checkpoint *Checkpoint
+	if opt.Checkpoint != nil {
+		var err error
+		if checkpoint, err = opt.Checkpoint.Load(); err != nil {
+			return nil, err
+		}
+	}
+	if checkpoint != nil {
+		if err := f.resume(checkpoint); err != nil {
+			return nil, err
+		}
+	} else {
+		switch opt.FromBlock {
+		case "", "latest", "pending":
+		case "earliest":
+			f.setStart(big.NewInt(0))
+		default:
+			start, err := common.DecodeQuantity(opt.FromBlock)
+			if err != nil {
+				return nil, err
+			}
+			f.setStart(start)
+		}
+	}
+
+	id, err := eth.installFilter(TypeBlockFilter, nil)
+	if err == nil {
+		f.filter = newFilter(eth, TypeBlockFilter, id, nil).(*baseFilter)
+	} else if code, ok := rpc.ErrorCode(err); !ok || code != rpc.CodeMethodNotFound {
+		return nil, err
+	}
+
+	f.wc = newWatchChannel(opt.Watch)
+	eth.requestManager.startPolling(f)
+	go f.wc.run(f.poll)
+	return f, nil
+}
+
+func (f *follower) Next() (*ChainEvent, error) {
+	if err := f.commit(); err != nil {
+		return nil, err
+	}
+
+	data, err := f.wc.Next()
+	if err != nil {
+		return nil, err
+	}
+	f.pending = data.(*ChainEvent)
+	return f.pending, nil
+}
+
+// commit saves the checkpoint of the event returned by the previous Next.
+func (f *follower) commit() error {
+	if f.pending == nil || f.option.Checkpoint == nil {
+		return nil
+	}
+
+	checkpoint := &Checkpoint{Number: f.pending.Block.Number, Hash: f.pending.Block.Hash}
+	if f.pending.Removed {
+		checkpoint.Number = new(big.Int).Sub(checkpoint.Number, big.NewInt(1))
+		checkpoint.Hash = f.pending.Block.ParentHash
+	}
+	if err := f.option.Checkpoint.Save(checkpoint); err != nil {
+		return err
+	}
+	f.pending = nil
+	return nil
+}
+
+// resume starts the follower after a checkpoint. The checkpoint block and,
+// if it left the canonical chain, its ancestors up to the canonical chain are
+// tracked as delivered, so the first poll reports them as removed.
+func (f *follower) resume(checkpoint *Checkpoint) error {
+	var branch []*followedBlock
+	hash := checkpoint.Hash
+	for uint64(len(branch)) < f.option.History {
+		block, err := f.eth.GetBlockByHash(hash, false)
+		if err != nil {
+			return err
+		}
+		if block.Hash != hash {
+			return ErrUnknownCheckpoint
+		}
+		canonical, err := f.eth.GetBlockByNumber(common.EncodeQuantity(block.Number), false)
+		if err != nil {
+			return err
+		}
+
+		fb := &followedBlock{block: block, emitted: true}
+		if canonical.Hash != block.Hash && f.option.Logs != nil {
+			if fb.logs, err = f.blockLogs(block); err != nil {
+				return err
+			}
+		}
+		branch = append([]*followedBlock{fb}, branch...)
+		if canonical.Hash == block.Hash || block.Number.Sign() == 0 {
+			// Blocks replacing the removed ones are delivered as well.
+			f.blocks = branch
+			f.start = new(big.Int).Add(block.Number, big.NewInt(1))
+			f.next = new(big.Int).Add(checkpoint.Number, big.NewInt(1))
+			return nil
+		}
+		hash = block.ParentHash
+	}
+	return ErrReorgTooDeep
+}
+
+// Close stops the follower and uninstalls its block filter.
+func (f *follower) Close() {
+	f.stop()
+}
+
+func (f *follower) stop() {
+	f.stopOnce.Do(func() {
+		f.wc.Close()
+		f.eth.requestManager.stopPolling(f)
+		if f.filter != nil {
+			f.eth.UninstallFilter(f.filter)
+		}
+	})
+}
+
+func (f *follower) syncing() bool {
+	return false
+}
+
+// setStart starts the walk at the parent of the first block delivered, to
+// detect a reorganization of that block.
+func (f *follower) setStart(start *big.Int) {
+	f.start = start
+	f.next = new(big.Int).Set(start)
+	if start.Sign() > 0 {
+		f.next.Sub(start, big.NewInt(1))
+	}
+}
+
+func (f *follower) poll() ([]interface{}, error) {
+	if f.filter != nil && f.next != nil && !f.behind {
+		changes, err := f.filter.poll()
+		if err != nil {
+			return nil, err
+		}
+		if len(changes) == 0 {
+			return nil, nil
+		}
+	}
+
+	head, err := f.eth.BlockNumber()
+	if err != nil {
+		return nil, err
+	}
+	if f.next == nil {
+		f.setStart(head)
+	}
+	if f.next.Cmp(head) > 0 && len(f.blocks) > 0 {
+		// No new height, check the head still is the tracked block.
+		f.next = new(big.Int).Set(head)
+	}
+
+	// Leave room in the history for the blocks waiting for confirmations.
+	steps := f.option.History - f.option.Confirmations
+	var events []interface{}
+	f.behind = false
+	for ; f.next.Cmp(head) <= 0; steps-- {
+		if steps == 0 {
+			f.behind = true
+			break
+		}
+		removed, err := f.advance()
+		if err != nil {
+			f.behind = true
+			if len(events) == 0 {
+				return nil, err
+			}
+			break
+		}
+		events = append(events, removed...)
+	}
+
+	added, err := f.confirm(head)
+	events = append(events, added...)
+	if err != nil {
+		f.behind = true
+		if len(events) == 0 {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// advance links the block at f.next to the tracked chain. If its parent is
+// not the tracked block below it, the chain is walked back by parent hash
+// until it joins the tracked blocks, and the replaced blocks are reported as
+// removed.
+func (f *follower) advance() ([]interface{}, error) {
+	block, err := f.eth.GetBlockByNumber(common.EncodeQuantity(f.next), false)
+	if err != nil {
+		return nil, err
+	}
+
+	// tip is the index of the tracked parent of block.
+	tip := len(f.blocks) - 1
+	if tip >= 0 {
+		last := f.blocks[tip].block.Number
+		tip -= int(new(big.Int).Sub(last, block.Number).Int64()) + 1
+	}
+
+	var branch []*common.Block
+	if tip+1 >= 0 && tip+1 < len(f.blocks) && f.blocks[tip+1].block.Hash == block.Hash {
+		tip++
+	} else {
+		branch = append(branch, block)
+		for ; tip >= 0 && branch[0].ParentHash != f.blocks[tip].block.Hash; tip-- {
+			parent, err := f.eth.GetBlockByHash(branch[0].ParentHash, false)
+			if err != nil {
+				return nil, err
+			}
+			branch = append([]*common.Block{parent}, branch...)
+		}
+		if tip < 0 && len(f.blocks) > 0 {
+			f.blocks = nil
+			f.next = block.Number
+			return nil, ErrReorgTooDeep
+		}
+	}
+
+	var events []interface{}
+	for i := len(f.blocks) - 1; i > tip; i-- {
+		if fb := f.blocks[i]; fb.emitted {
+			logs := make([]common.Log, len(fb.logs))
+			for j, log := range fb.logs {
+				log.Removed = true
+				logs[j] = log
+			}
+			events = append(events, &ChainEvent{Removed: true, Block: fb.block, Logs: logs})
+		}
+	}
+
+	f.blocks = f.blocks[:tip+1]
+	for _, b := range branch {
+		f.blocks = append(f.blocks, &followedBlock{block: b})
+	}
+	if over := len(f.blocks) - int(f.option.History); over > 0 {
+		f.blocks = f.blocks[over:]
+	}
+	f.next = new(big.Int).Add(block.Number, big.NewInt(1))
+	return events, nil
+}
+
+// confirm reports the tracked blocks with enough confirmations at head.
+func (f *follower) confirm(head *big.Int) ([]interface{}, error) {
+	confirmed := new(big.Int).Sub(head, new(big.Int).SetUint64(f.option.Confirmations))
+
+	var events []interface{}
+	for _, fb := range f.blocks {
+		if fb.emitted || fb.block.Number.Cmp(f.start) < 0 || fb.block.Number.Cmp(confirmed) > 0 {
+			continue
+		}
+		if f.option.Logs != nil {
+			logs, err := f.blockLogs(fb.block)
+			if err != nil {
+				return events, err
+			}
+			fb.logs = logs
+		}
+		fb.emitted = true
+		events = append(events, &ChainEvent{Block: fb.block, Logs: fb.logs})
+	}
+	return events, nil
+}
+
+// blockLogs fetches the logs of a block by hash, so they can't come from
+// another block at the same height.
+func (f *follower) blockLogs(block *common.Block) ([]common.Log, error) {
+	option := *f.option.Logs
+	option.FromBlock = ""
+	option.ToBlock = ""
+	option.BlockHash = block.Hash.String()
+	return f.eth.GetLogs(&option)
+}
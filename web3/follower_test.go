@@ -0,0 +1,320 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alanchchen/web3go/common"
+	"github.com/alanchchen/web3go/provider"
+	"github.com/alanchchen/web3go/rpc"
+	"github.com/alanchchen/web3go/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// chainProvider simulates a chain that can be extended and reorganized.
+// Blocks of a fork are told apart by the first byte of their hash. Blocks
+// and logs are sent hex encoded, as nodes do.
+type chainProvider struct {
+	provider.Provider
+	noFilters bool
+
+	lock      sync.Mutex
+	chain     []*common.Block
+	blocks    map[common.Hash]*common.Block
+	changed   bool
+	uninstall int
+}
+
+func newChainProvider(head int) *chainProvider {
+	p := &chainProvider{
+		Provider: test.NewMockHTTPProvider(),
+		blocks:   make(map[common.Hash]*common.Block),
+	}
+	p.extend(head+1, 0)
+	return p
+}
+
+func (p *chainProvider) extend(count int, fork byte) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for i := 0; i < count; i++ {
+		number := len(p.chain)
+		block := &common.Block{
+			Number: big.NewInt(int64(number)),
+			Hash:   common.NewHash([]byte{fork, byte(number), 1}),
+		}
+		if number > 0 {
+			block.ParentHash = p.chain[number-1].Hash
+		}
+		p.chain = append(p.chain, block)
+		p.blocks[block.Hash] = block
+	}
+	p.changed = true
+}
+
+// reorg replaces the last depth blocks with blocks of another fork.
+func (p *chainProvider) reorg(depth int, fork byte) {
+	p.lock.Lock()
+	p.chain = p.chain[:len(p.chain)-depth]
+	p.lock.Unlock()
+	p.extend(depth, fork)
+}
+
+func (p *chainProvider) Send(request rpc.Request) (rpc.Response, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var params []interface{}
+	if ps, ok := request.Get("params").([]interface{}); ok {
+		params = ps
+	}
+
+	var result interface{}
+	switch request.Get("method").(string) {
+	case "eth_blockNumber":
+		result = common.EncodeUint64Quantity(uint64(len(p.chain) - 1))
+	case "eth_getBlockByNumber":
+		number, _ := common.DecodeUint64Quantity(params[0].(string))
+		result = test.NodeBlock(p.chain[number])
+	case "eth_getBlockByHash":
+		hash, _ := common.ParseHash(params[0].(string))
+		if block, ok := p.blocks[hash]; ok {
+			result = test.NodeBlock(block)
+		}
+	case "eth_newBlockFilter":
+		if p.noFilters {
+			raw := fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"error":{"code":-32601,"message":"Method not found"}}`, request.ID())
+			return p.GetRPCMethod().NewResponse([]byte(raw)), nil
+		}
+		result = "0x1"
+	case "eth_getFilterChanges":
+		changes := []string{}
+		if p.changed {
+			tip := p.chain[len(p.chain)-1]
+			changes = append(changes, tip.Hash.String())
+		}
+		p.changed = false
+		result = changes
+	case "eth_uninstallFilter":
+		p.uninstall++
+		result = true
+	case "eth_getLogs":
+		option := params[0].(*FilterOption)
+		hash, _ := common.ParseHash(option.BlockHash)
+		block := p.blocks[hash]
+		result = test.NodeLogs([]common.Log{{BlockNumber: block.Number, BlockHash: block.Hash}})
+	default:
+		return p.Provider.Send(request)
+	}
+
+	data, _ := json.Marshal(result)
+	raw := fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":%s}`, request.ID(), data)
+	return p.GetRPCMethod().NewResponse([]byte(raw)), nil
+}
+
+type FollowerTestSuite struct {
+	suite.Suite
+	provider *chainProvider
+	eth      Eth
+}
+
+func (suite *FollowerTestSuite) follow(option *FollowOption) Follower {
+	option.Watch = &WatchOption{PollInterval: time.Millisecond}
+	follower, err := suite.eth.Follow(option)
+	if !assert.NoError(suite.T(), err, "Should be no error") {
+		suite.T().FailNow()
+	}
+	return follower
+}
+
+// expect reads events and compares them with the expected block numbers and
+// forks, negative numbers for removed blocks.
+func (suite *FollowerTestSuite) expect(follower Follower, numbers []int64, forks []byte) {
+	for i, number := range numbers {
+		event, err := follower.Next()
+		if !assert.NoError(suite.T(), err, "Should be no error") {
+			return
+		}
+		assert.Equal(suite.T(), number < 0, event.Removed, "Should be equal")
+		if number < 0 {
+			number = -number
+		}
+		assert.Equal(suite.T(), number, event.Block.Number.Int64(), "Should be equal")
+		assert.Equal(suite.T(), forks[i], event.Block.Hash[0], "Should be equal")
+	}
+}
+
+func (suite *FollowerTestSuite) Test_Follow() {
+	follower := suite.follow(&FollowOption{FromBlock: "0x8"})
+	defer follower.Close()
+	suite.expect(follower, []int64{8, 9, 10}, []byte{0, 0, 0})
+
+	suite.provider.extend(1, 0)
+	suite.expect(follower, []int64{11}, []byte{0})
+
+	suite.provider.reorg(2, 1)
+	suite.provider.extend(1, 1)
+	suite.expect(follower, []int64{-11, -10, 10, 11, 12}, []byte{0, 0, 1, 1, 1})
+}
+
+func (suite *FollowerTestSuite) Test_Head() {
+	follower := suite.follow(&FollowOption{})
+	defer follower.Close()
+	suite.expect(follower, []int64{10}, []byte{0})
+}
+
+func (suite *FollowerTestSuite) Test_Confirmations() {
+	follower := suite.follow(&FollowOption{FromBlock: "0x5", Confirmations: 2})
+	defer follower.Close()
+	suite.expect(follower, []int64{5, 6, 7, 8}, []byte{0, 0, 0, 0})
+
+	// Unconfirmed blocks are replaced silently.
+	suite.provider.reorg(2, 1)
+	suite.provider.extend(1, 1)
+	suite.expect(follower, []int64{9}, []byte{1})
+}
+
+func (suite *FollowerTestSuite) Test_Logs() {
+	follower := suite.follow(&FollowOption{FromBlock: "0xa", Logs: &FilterOption{FromBlock: "0x0"}})
+	defer follower.Close()
+
+	event, err := follower.Next()
+	assert.NoError(suite.T(), err, "Should be no error")
+	if assert.Len(suite.T(), event.Logs, 1, "Should be equal") {
+		assert.Equal(suite.T(), event.Block.Hash, event.Logs[0].BlockHash, "Should be equal")
+		assert.False(suite.T(), event.Logs[0].Removed, "Should be false")
+	}
+
+	suite.provider.reorg(1, 1)
+	event, err = follower.Next()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.True(suite.T(), event.Removed, "Should be true")
+	if assert.Len(suite.T(), event.Logs, 1, "Should be equal") {
+		assert.True(suite.T(), event.Logs[0].Removed, "Should be true")
+	}
+}
+
+func (suite *FollowerTestSuite) Test_ReorgTooDeep() {
+	follower := suite.follow(&FollowOption{FromBlock: "0x8", History: 2})
+	defer follower.Close()
+	suite.expect(follower, []int64{8, 9, 10}, []byte{0, 0, 0})
+
+	suite.provider.reorg(3, 1)
+	_, err := follower.Next()
+	assert.Equal(suite.T(), ErrReorgTooDeep, err, "Should be equal")
+	suite.expect(follower, []int64{10}, []byte{1})
+}
+
+func (suite *FollowerTestSuite) Test_WithoutFilters() {
+	suite.provider.noFilters = true
+	follower := suite.follow(&FollowOption{FromBlock: "0xa"})
+	defer follower.Close()
+	suite.expect(follower, []int64{10}, []byte{0})
+
+	suite.provider.extend(1, 0)
+	suite.expect(follower, []int64{11}, []byte{0})
+}
+
+func (suite *FollowerTestSuite) Test_Close() {
+	follower := suite.follow(&FollowOption{})
+	follower.Close()
+	follower.Close()
+
+	for {
+		if _, err := follower.Next(); err == ErrChannelClosed {
+			break
+		}
+	}
+	assert.Equal(suite.T(), 1, suite.provider.uninstall, "Should be equal")
+}
+
+func (suite *FollowerTestSuite) Test_Checkpoint() {
+	store := NewMemoryCheckpointStore()
+	follower := suite.follow(&FollowOption{FromBlock: "0x8", Checkpoint: store})
+	suite.expect(follower, []int64{8, 9}, []byte{0, 0})
+	follower.Close()
+
+	checkpoint, err := store.Load()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), int64(8), checkpoint.Number.Int64(), "Should be equal")
+
+	// The last event is delivered again, FromBlock is ignored.
+	follower = suite.follow(&FollowOption{FromBlock: "0x0", Checkpoint: store})
+	defer follower.Close()
+	event, err := follower.Next()
+	if assert.NoError(suite.T(), err, "Should be no error") {
+		assert.Equal(suite.T(), int64(9), event.Block.Number.Int64(), "Should be equal")
+		assert.Equal(suite.T(), checkpoint.Hash, event.Block.ParentHash, "Should be equal")
+	}
+	suite.expect(follower, []int64{10}, []byte{0})
+}
+
+func (suite *FollowerTestSuite) Test_CheckpointReorg() {
+	store := NewMemoryCheckpointStore()
+	follower := suite.follow(&FollowOption{FromBlock: "0x9", Checkpoint: store, Logs: &FilterOption{}})
+	suite.expect(follower, []int64{9, 10}, []byte{0, 0})
+	suite.provider.extend(1, 0)
+	suite.expect(follower, []int64{11}, []byte{0})
+	follower.Close()
+
+	// The chain is reorganized while the follower is down.
+	suite.provider.reorg(2, 1)
+	follower = suite.follow(&FollowOption{Checkpoint: store, Logs: &FilterOption{}})
+	defer follower.Close()
+	suite.expect(follower, []int64{-10, 10, 11}, []byte{0, 1, 1})
+
+	// Removed events move the checkpoint to the parent block.
+	checkpoint, err := store.Load()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), int64(10), checkpoint.Number.Int64(), "Should be equal")
+}
+
+func (suite *FollowerTestSuite) Test_UnknownCheckpoint() {
+	store := NewMemoryCheckpointStore()
+	store.Save(&Checkpoint{Number: big.NewInt(5), Hash: common.NewHash([]byte{9})})
+	_, err := suite.eth.Follow(&FollowOption{Checkpoint: store})
+	assert.Equal(suite.T(), ErrUnknownCheckpoint, err, "Should be equal")
+}
+
+func (suite *FollowerTestSuite) SetupTest() {
+	suite.provider = newChainProvider(10)
+	suite.eth = NewWeb3(suite.provider).Eth
+}
+
+func Test_FollowerTestSuite(t *testing.T) {
+	suite.Run(t, new(FollowerTestSuite))
+}
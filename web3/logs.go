@@ -0,0 +1,312 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/alanchchen/web3go/common"
+	"github.com/alanchchen/web3go/rpc"
+)
+
+var (
+	// ErrInvalidBlockRange is returned when the FromBlock of a chunked query
+	// is after its ToBlock.
+	ErrInvalidBlockRange = errors.New("Invalid block range")
+	ErrUnknownBlockTag   = errors.New("Block tag is unknown to the node")
+)
+
+const (
+	logWindowSize  = 2000
+	logParallelism = 4
+)
+
+// LogRangeOption controls how GetLogsChunked splits a block range. Zero values
+// mean defaults.
+type LogRangeOption struct {
+	// WindowSize is the number of blocks queried at once, 2000 by default.
+	// It is halved whenever the node reports too many results.
+	WindowSize uint64
+	// MinWindowSize is the smallest window before giving up, 1 by default.
+	MinWindowSize uint64
+	// Parallelism is the number of windows queried concurrently, 4 by
+	// default.
+	Parallelism int
+}
+
+func (opt *LogRangeOption) withDefaults() LogRangeOption {
+	result := LogRangeOption{}
+	if opt != nil {
+		result = *opt
+	}
+	if result.WindowSize == 0 {
+		result.WindowSize = logWindowSize
+	}
+	if result.MinWindowSize == 0 {
+		result.MinWindowSize = 1
+	}
+	if result.MinWindowSize > result.WindowSize {
+		result.MinWindowSize = result.WindowSize
+	}
+	if result.Parallelism <= 0 {
+		result.Parallelism = logParallelism
+	}
+	return result
+}
+
+// GetLogsChunked returns all logs matching a given filter object like
+// GetLogs, but splits the block range into windows queried concurrently.
+// Block tags are resolved once, "latest" and "pending" to the current block
+// number, "safe" and "finalized" to the number of the tagged block, and
+// "earliest" to block 0. A missing FromBlock or ToBlock means
+// "latest", as for eth_getLogs. Queries by BlockHash are sent as they are.
+//
+// When the node refuses a window because of too many results, the window is
+// halved and retried, and later windows use the smaller size. Logs are
+// returned in block order.
+func (eth *EthAPI) GetLogsChunked(option *FilterOption, rangeOption *LogRangeOption) ([]common.Log, error) {
+	if option == nil {
+		option = &FilterOption{}
+	}
+	if option.BlockHash != "" {
+		return eth.GetLogs(option)
+	}
+	opt := rangeOption.withDefaults()
+
+	var latest *big.Int
+	resolve := func(tag string) (uint64, error) {
+		switch tag {
+		case "earliest":
+			return 0, nil
+		case "", "latest", "pending":
+			if latest == nil {
+				var err error
+				if latest, err = eth.BlockNumber(); err != nil {
+					return 0, err
+				}
+			}
+			return latest.Uint64(), nil
+		case "safe", "finalized":
+			number, err := eth.taggedBlockNumber(tag)
+			if err != nil {
+				return 0, err
+			}
+			return number.Uint64(), nil
+		}
+		return common.DecodeUint64Quantity(tag)
+	}
+	from, err := resolve(option.FromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := resolve(option.ToBlock)
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, ErrInvalidBlockRange
+	}
+
+	q := &logQuery{
+		eth:     eth,
+		option:  *option,
+		next:    from,
+		to:      to,
+		size:    opt.WindowSize,
+		minSize: opt.MinWindowSize,
+		results: make(map[uint64][]common.Log),
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < opt.Parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work()
+		}()
+	}
+	wg.Wait()
+
+	if q.err != nil {
+		return nil, q.err
+	}
+	return q.logs(), nil
+}
+
+// taggedBlockNumber returns the number of the block of a tag such as
+// "finalized", which nodes without the tag don't know.
+func (eth *EthAPI) taggedBlockNumber(tag string) (*big.Int, error) {
+	block, err := eth.GetBlockByNumber(tag, false)
+	if err != nil {
+		return nil, err
+	}
+	if block.Number == nil {
+		return nil, ErrUnknownBlockTag
+	}
+	return block.Number, nil
+}
+
+// logWindow is an inclusive block range.
+type logWindow struct {
+	from, to uint64
+}
+
+// logQuery hands out windows to workers. Windows are cut from next with the
+// current size. Refused windows are queued in retry and cut again with the
+// reduced size.
+type logQuery struct {
+	eth    *EthAPI
+	option FilterOption
+
+	lock    sync.Mutex
+	next    uint64
+	to      uint64
+	done    bool
+	size    uint64
+	minSize uint64
+	retry   []logWindow
+	results map[uint64][]common.Log
+	err     error
+}
+
+func (q *logQuery) work() {
+	for {
+		w, ok := q.take()
+		if !ok {
+			return
+		}
+
+		option := q.option
+		option.FromBlock = common.EncodeUint64Quantity(w.from)
+		option.ToBlock = common.EncodeUint64Quantity(w.to)
+		logs, err := q.eth.GetLogs(&option)
+		q.complete(w, logs, err)
+	}
+}
+
+func (q *logQuery) take() (logWindow, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.err != nil {
+		return logWindow{}, false
+	}
+	if n := len(q.retry); n > 0 {
+		w := q.retry[n-1]
+		q.retry = q.retry[:n-1]
+		if w.to-w.from >= q.size {
+			q.retry = append(q.retry, logWindow{from: w.from + q.size, to: w.to})
+			w.to = w.from + q.size - 1
+		}
+		return w, true
+	}
+	if q.done {
+		return logWindow{}, false
+	}
+
+	w := logWindow{from: q.next, to: q.to}
+	if q.to-q.next >= q.size {
+		w.to = q.next + q.size - 1
+	}
+	if w.to == q.to {
+		q.done = true
+	} else {
+		q.next = w.to + 1
+	}
+	return w, true
+}
+
+func (q *logQuery) complete(w logWindow, logs []common.Log, err error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if err == nil {
+		q.results[w.from] = logs
+		return
+	}
+
+	blocks := w.to - w.from + 1
+	if !isTooManyResults(err) || blocks <= q.minSize {
+		if q.err == nil {
+			q.err = err
+		}
+		return
+	}
+
+	if half := blocks / 2; half < q.size {
+		q.size = half
+		if q.size < q.minSize {
+			q.size = q.minSize
+		}
+	}
+	q.retry = append(q.retry, w)
+}
+
+// logs concatenates the results of all windows in block order.
+func (q *logQuery) logs() []common.Log {
+	starts := make([]uint64, 0, len(q.results))
+	for start := range q.results {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	logs := make([]common.Log, 0)
+	for _, start := range starts {
+		logs = append(logs, q.results[start]...)
+	}
+	return logs
+}
+
+// tooManyResultsMessages are fragments of the errors nodes and providers
+// return when a log query is too large.
+var tooManyResultsMessages = []string{
+	"query returned more than",
+	"too many results",
+	"response size exceeded",
+	"response size is larger",
+	"block range",
+	"limit exceeded",
+}
+
+func isTooManyResults(err error) bool {
+	if code, ok := rpc.ErrorCode(err); ok && code == rpc.CodeLimitExceeded {
+		return true
+	}
+	message := strings.ToLower(err.Error())
+	for _, fragment := range tooManyResultsMessages {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/alanchchen/web3go/common"
+	"github.com/alanchchen/web3go/provider"
+	"github.com/alanchchen/web3go/rpc"
+	"github.com/alanchchen/web3go/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// rangeProvider answers eth_getLogs with one log per block, and refuses
+// ranges larger than limit blocks. Blocks by number are answered with null
+// if untagged is set.
+type rangeProvider struct {
+	provider.Provider
+	limit    uint64
+	fail     string
+	untagged bool
+
+	lock    sync.Mutex
+	queries []logWindow
+	refused int
+}
+
+func (p *rangeProvider) Send(request rpc.Request) (rpc.Response, error) {
+	switch request.Get("method").(string) {
+	case "eth_getLogs":
+	case "eth_getBlockByNumber":
+		if p.untagged {
+			raw := fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":null}`, request.ID())
+			return p.GetRPCMethod().NewResponse([]byte(raw)), nil
+		}
+		return p.Provider.Send(request)
+	default:
+		return p.Provider.Send(request)
+	}
+
+	option := request.Get("params").([]interface{})[0].(*FilterOption)
+	from, _ := common.DecodeUint64Quantity(option.FromBlock)
+	to, _ := common.DecodeUint64Quantity(option.ToBlock)
+
+	p.lock.Lock()
+	p.queries = append(p.queries, logWindow{from, to})
+	refuse := p.fail == "" && to-from+1 > p.limit
+	if refuse {
+		p.refused++
+	}
+	p.lock.Unlock()
+
+	var raw string
+	switch {
+	case p.fail != "":
+		raw = fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"error":{"code":-32000,"message":%q}}`, request.ID(), p.fail)
+	case refuse:
+		raw = fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"error":{"code":-32005,"message":"query returned more than %d results"}}`, request.ID(), p.limit)
+	default:
+		logs := make([]map[string]interface{}, 0)
+		for block := from; block <= to; block++ {
+			logs = append(logs, map[string]interface{}{"blockNumber": block, "logIndex": 0})
+		}
+		result, _ := json.Marshal(logs)
+		raw = fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":%s}`, request.ID(), result)
+	}
+	return p.GetRPCMethod().NewResponse([]byte(raw)), nil
+}
+
+type LogsTestSuite struct {
+	suite.Suite
+	provider *rangeProvider
+	eth      Eth
+}
+
+func (suite *LogsTestSuite) assertBlocks(logs []common.Log, from, to uint64) {
+	if assert.Len(suite.T(), logs, int(to-from+1), "Should be equal") {
+		for i, log := range logs {
+			assert.Equal(suite.T(), from+uint64(i), log.BlockNumber.Uint64(), "Should be in block order")
+		}
+	}
+}
+
+func (suite *LogsTestSuite) Test_Windows() {
+	option := &FilterOption{FromBlock: "0x0", ToBlock: "0x63"}
+	logs, err := suite.eth.GetLogsChunked(option, &LogRangeOption{WindowSize: 10, Parallelism: 3})
+	assert.NoError(suite.T(), err, "Should be no error")
+	suite.assertBlocks(logs, 0, 99)
+	assert.Len(suite.T(), suite.provider.queries, 10, "Should be equal")
+	assert.Equal(suite.T(), "0x0", option.FromBlock, "Option should not be modified")
+}
+
+func (suite *LogsTestSuite) Test_AdaptiveWindow() {
+	suite.provider.limit = 8
+	option := &FilterOption{FromBlock: "0x0", ToBlock: "0x63"}
+	logs, err := suite.eth.GetLogsChunked(option, &LogRangeOption{WindowSize: 64, Parallelism: 1})
+	assert.NoError(suite.T(), err, "Should be no error")
+	suite.assertBlocks(logs, 0, 99)
+	// 64 -> 32 -> 16 -> 8, then every window fits.
+	assert.Equal(suite.T(), 3, suite.provider.refused, "Should be equal")
+}
+
+func (suite *LogsTestSuite) Test_MinWindowSize() {
+	suite.provider.limit = 4
+	option := &FilterOption{FromBlock: "0x0", ToBlock: "0x63"}
+	_, err := suite.eth.GetLogsChunked(option, &LogRangeOption{WindowSize: 64, MinWindowSize: 16})
+	assert.True(suite.T(), isTooManyResults(err), "Should give up at the minimum window size")
+}
+
+func (suite *LogsTestSuite) Test_Latest() {
+	option := &FilterOption{FromBlock: "0x4b0"}
+	logs, err := suite.eth.GetLogsChunked(option, nil)
+	assert.NoError(suite.T(), err, "Should be no error")
+	suite.assertBlocks(logs, 0x4b0, 0x4b7)
+}
+
+func (suite *LogsTestSuite) Test_Finalized() {
+	option := &FilterOption{FromBlock: "0x1b0", ToBlock: "finalized"}
+	logs, err := suite.eth.GetLogsChunked(option, nil)
+	assert.NoError(suite.T(), err, "Should be no error")
+	suite.assertBlocks(logs, 0x1b0, 0x1b4)
+
+	suite.provider.untagged = true
+	option = &FilterOption{FromBlock: "0x1b0", ToBlock: "safe"}
+	_, err = suite.eth.GetLogsChunked(option, nil)
+	assert.Equal(suite.T(), ErrUnknownBlockTag, err, "Should be equal")
+}
+
+func (suite *LogsTestSuite) Test_InvalidRange() {
+	option := &FilterOption{FromBlock: "0x10", ToBlock: "0x1"}
+	_, err := suite.eth.GetLogsChunked(option, nil)
+	assert.Equal(suite.T(), ErrInvalidBlockRange, err, "Should be equal")
+}
+
+func (suite *LogsTestSuite) Test_Error() {
+	suite.provider.fail = "Internal error"
+	option := &FilterOption{FromBlock: "0x0", ToBlock: "0x63"}
+	_, err := suite.eth.GetLogsChunked(option, &LogRangeOption{WindowSize: 10, Parallelism: 1})
+	assert.EqualError(suite.T(), err, "Internal error", "Should be equal")
+	assert.Len(suite.T(), suite.provider.queries, 1, "Should stop at the first error")
+}
+
+func (suite *LogsTestSuite) Test_IsTooManyResults() {
+	assert.True(suite.T(), isTooManyResults(fmt.Errorf("Log response size exceeded")), "Should be true")
+	assert.True(suite.T(), isTooManyResults(fmt.Errorf("exceed maximum block range: 5000")), "Should be true")
+	assert.False(suite.T(), isTooManyResults(fmt.Errorf("Connection refused")), "Should be false")
+}
+
+func (suite *LogsTestSuite) SetupTest() {
+	suite.provider = &rangeProvider{
+		Provider: test.NewMockHTTPProvider(),
+		limit:    10000,
+	}
+	suite.eth = NewWeb3(suite.provider).Eth
+}
+
+func Test_LogsTestSuite(t *testing.T) {
+	suite.Run(t, new(LogsTestSuite))
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"math/big"
+
+	"github.com/alanchchen/web3go/common"
+)
+
+// LogMatcher applies the criteria of a FilterOption to logs obtained
+// elsewhere than eth_getLogs, e.g. from receipts.
+type LogMatcher struct {
+	filter common.LogFilter
+}
+
+// NewLogMatcher validates option and creates a matcher for it. head is the
+// number of the chain head block, which a missing block, "latest" and
+// "pending" stand for as in eth_getLogs, see Eth.BlockNumber. A nil head
+// leaves these tags unbounded, like "safe" and "finalized".
+func NewLogMatcher(option *FilterOption, head *big.Int) (*LogMatcher, error) {
+	if option == nil {
+		option = &FilterOption{}
+	}
+	if err := option.Validate(); err != nil {
+		return nil, err
+	}
+
+	m := &LogMatcher{}
+	if option.BlockHash == "" {
+		m.filter.FromBlock, _ = common.DecodeBlockBound(option.FromBlock, head)
+		m.filter.ToBlock, _ = common.DecodeBlockBound(option.ToBlock, head)
+	}
+	if option.BlockHash != "" {
+		hash, _ := common.ParseHash(option.BlockHash)
+		m.filter.BlockHash = &hash
+	}
+	m.filter.Addresses, _ = option.Addresses()
+	for _, alternatives := range option.Topics {
+		m.filter.Topics = append(m.filter.Topics, alternatives)
+	}
+	return m, nil
+}
+
+// Match tells if log satisfies the criteria.
+func (m *LogMatcher) Match(log common.Log) bool {
+	return m.filter.Match(&log)
+}
+
+// Filter returns the logs satisfying the criteria, in the same order.
+func (m *LogMatcher) Filter(logs []common.Log) []common.Log {
+	matched := make([]common.Log, 0)
+	for i := range logs {
+		if m.filter.Match(&logs[i]) {
+			matched = append(matched, logs[i])
+		}
+	}
+	return matched
+}
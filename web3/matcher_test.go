@@ -0,0 +1,130 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/alanchchen/web3go/common"
+	"github.com/alanchchen/web3go/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MatcherTestSuite struct {
+	suite.Suite
+	logs []common.Log
+}
+
+func (suite *MatcherTestSuite) Test_Filter() {
+	tests := []struct {
+		builder *FilterBuilder
+		blocks  []int64
+	}{
+		{NewFilterBuilder(), []int64{3}},
+		{NewFilterBuilder().FromBlock("0x2"), []int64{2, 3}},
+		{NewFilterBuilder().BlockRange(2, 3), []int64{2, 3}},
+		{NewFilterBuilder().FromBlock("earliest").ToBlock("0x1"), []int64{1}},
+		{NewFilterBuilder().FromBlock("earliest").Address(testAddress2), []int64{2}},
+		{NewFilterBuilder().FromBlock("earliest").Address(testAddress1, testAddress2), []int64{1, 2, 3}},
+		{NewFilterBuilder().FromBlock("earliest").Topic(0, testTopic1), []int64{1, 2}},
+		{NewFilterBuilder().FromBlock("earliest").Topic(1, testTopic1, testTopic2), []int64{2}},
+		{NewFilterBuilder().FromBlock("earliest").Topic(0).Topic(1), []int64{2}},
+		{NewFilterBuilder().FromBlock("finalized"), []int64{1, 2, 3}},
+	}
+	for _, test := range tests {
+		option, err := test.builder.Build()
+		assert.NoError(suite.T(), err, "Should be no error")
+		matcher, err := NewLogMatcher(option, big.NewInt(3))
+		assert.NoError(suite.T(), err, "Should be no error")
+
+		blocks := []int64{}
+		for _, log := range matcher.Filter(suite.logs) {
+			blocks = append(blocks, log.BlockNumber.Int64())
+		}
+		assert.Equal(suite.T(), test.blocks, blocks, option.String())
+	}
+}
+
+func (suite *MatcherTestSuite) Test_BlockHash() {
+	option, _ := NewFilterBuilder().BlockHash(testTopic2).Build()
+	matcher, err := NewLogMatcher(option, big.NewInt(10))
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.True(suite.T(), matcher.Match(suite.logs[2]), "Should be true")
+	assert.False(suite.T(), matcher.Match(suite.logs[0]), "Should be false")
+}
+
+func (suite *MatcherTestSuite) Test_Invalid() {
+	_, err := NewLogMatcher(&FilterOption{Address: 42}, nil)
+	assert.Equal(suite.T(), ErrInvalidAddressType, err, "Should be equal")
+}
+
+func (suite *MatcherTestSuite) Test_UnknownHead() {
+	matcher, err := NewLogMatcher(nil, nil)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Len(suite.T(), matcher.Filter(suite.logs), 3, "Should be unbounded")
+}
+
+// The mock provider applies the same criteria to eth_getLogs.
+func (suite *MatcherTestSuite) Test_MockGetLogs() {
+	eth := NewWeb3(test.NewMockHTTPProvider()).Eth
+	logs, err := eth.GetLogs(&FilterOption{Topics: TopicFilter{{testTopic1}}})
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Len(suite.T(), logs, 1, "Should be equal")
+
+	logs, err = eth.GetLogs(&FilterOption{Address: testAddress2})
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Empty(suite.T(), logs, "Should be empty")
+}
+
+func (suite *MatcherTestSuite) SetupTest() {
+	suite.logs = []common.Log{
+		{
+			BlockNumber: big.NewInt(1),
+			Address:     testAddress1,
+			Topics:      common.Topics{{Data: testTopic1[:]}},
+		},
+		{
+			BlockNumber: big.NewInt(2),
+			Address:     testAddress2,
+			Topics:      common.Topics{{Data: testTopic1[:]}, {Data: testTopic2[:]}},
+		},
+		{
+			BlockNumber: big.NewInt(3),
+			BlockHash:   testTopic2,
+			Address:     testAddress1,
+		},
+	}
+}
+
+func Test_MatcherTestSuite(t *testing.T) {
+	suite.Run(t, new(MatcherTestSuite))
+}
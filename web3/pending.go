@@ -0,0 +1,361 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/alanchchen/web3go/common"
+	"github.com/alanchchen/web3go/rpc"
+)
+
+const (
+	pendingConcurrency   = 8
+	pendingBatchSize     = 100
+	pendingDedupSize     = 4096
+	pendingFetchAttempts = 3
+)
+
+// PendingTransactionOption configures WatchPendingTransactionDetails. Zero
+// fields take defaults, empty sets match any transaction.
+type PendingTransactionOption struct {
+	// From and To are the accepted senders and recipients.
+	From []common.Address
+	To   []common.Address
+	// Selectors are the accepted 4-byte method selectors, matched against
+	// the start of the input data.
+	Selectors [][]byte
+	// Concurrency bounds the requests in flight at once, single requests or
+	// batches, 8 by default.
+	Concurrency int
+	// BatchSize bounds the hashes fetched in one batch when the provider
+	// batches requests, 100 by default. Nodes reject batches above a limit,
+	// 1000 for geth.
+	BatchSize int
+	// DedupSize is the number of recent hashes remembered to drop duplicates,
+	// 4096 by default.
+	DedupSize int
+	// Watch configures polling and buffering.
+	Watch *WatchOption
+}
+
+// PendingFetchError reports pending transactions given up on because their
+// fetches kept failing.
+type PendingFetchError struct {
+	Hashes []common.Hash
+	// Err is the error of the last failed fetch.
+	Err error
+}
+
+func (e *PendingFetchError) Error() string {
+	return fmt.Sprintf("Failed to fetch %d pending transactions: %v", len(e.Hashes), e.Err)
+}
+
+// Unwrap returns the error of the last failed fetch.
+func (e *PendingFetchError) Unwrap() error {
+	return e.Err
+}
+
+// TransactionWatcher delivers pending transactions.
+type TransactionWatcher interface {
+	Next() (*common.Transaction, error)
+	Transactions() <-chan *common.Transaction
+	Errors() <-chan error
+	Close()
+}
+
+// WatchPendingTransactionDetails installs a pending transaction filter and
+// delivers the matching transactions. Hashes are fetched concurrently, in
+// batches if the provider supports it. Transactions dropped from the
+// pool before they are fetched are skipped, failed fetches are retried on
+// the next polls. Transactions still failing after 3 attempts are reported
+// with a *PendingFetchError, after the transactions fetched by the same poll.
+func (eth *EthAPI) WatchPendingTransactionDetails(option *PendingTransactionOption) (TransactionWatcher, error) {
+	opt := PendingTransactionOption{}
+	if option != nil {
+		opt = *option
+	}
+	if opt.Concurrency <= 0 {
+		opt.Concurrency = pendingConcurrency
+	}
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = pendingBatchSize
+	}
+	if opt.DedupSize <= 0 {
+		opt.DedupSize = pendingDedupSize
+	}
+
+	filter, err := eth.NewPendingTransactionFilter()
+	if err != nil {
+		return nil, err
+	}
+	s := &pendingStream{
+		eth:    eth,
+		option: opt,
+		filter: filter.(*baseFilter),
+		seen:   make(map[common.Hash]struct{}),
+		retry:  make(map[common.Hash]int),
+	}
+
+	wc := newWatchChannel(opt.Watch)
+	s.filter.watchers.add(wc)
+	go wc.run(s.poll)
+
+	w := &transactionWatcher{ch: make(chan *common.Transaction)}
+	decode := func(data interface{}) (interface{}, error) {
+		return data, nil
+	}
+	w.typedWatch = newTypedWatch(eth, filter, wc, decode, w.ch)
+	return w, nil
+}
+
+// pendingStream resolves the hashes of a pending transaction filter. It is
+// only used by the polling goroutine.
+type pendingStream struct {
+	eth    *EthAPI
+	option PendingTransactionOption
+	filter *baseFilter
+
+	// seen and order hold the recent hashes, order is a ring.
+	seen  map[common.Hash]struct{}
+	order []common.Hash
+	next  int
+	// retry counts the failed fetches of hashes.
+	retry map[common.Hash]int
+}
+
+func (s *pendingStream) poll() ([]interface{}, error) {
+	changes, err := s.filter.poll()
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]common.Hash, 0, len(changes)+len(s.retry))
+	for hash := range s.retry {
+		hashes = append(hashes, hash)
+	}
+	for _, change := range changes {
+		h, err := decodeHash(change)
+		if err != nil {
+			return nil, err
+		}
+		if hash := h.(common.Hash); s.markSeen(hash) {
+			hashes = append(hashes, hash)
+		}
+	}
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+
+	txs, errs := s.fetch(hashes)
+	var results []interface{}
+	var lastErr error
+	var failed *PendingFetchError
+	for i, hash := range hashes {
+		if errs[i] != nil {
+			lastErr = errs[i]
+			if s.retry[hash]++; s.retry[hash] >= pendingFetchAttempts {
+				delete(s.retry, hash)
+				if failed == nil {
+					failed = &PendingFetchError{}
+				}
+				failed.Hashes = append(failed.Hashes, hash)
+				failed.Err = errs[i]
+			}
+			continue
+		}
+		delete(s.retry, hash)
+		if txs[i] != nil && s.match(txs[i]) {
+			results = append(results, txs[i])
+		}
+	}
+	if failed != nil {
+		return results, failed
+	}
+	if len(results) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return results, nil
+}
+
+// markSeen remembers hash, it returns false if it was seen already.
+func (s *pendingStream) markSeen(hash common.Hash) bool {
+	if _, ok := s.seen[hash]; ok {
+		return false
+	}
+	if len(s.order) < s.option.DedupSize {
+		s.order = append(s.order, hash)
+	} else {
+		delete(s.seen, s.order[s.next])
+		s.order[s.next] = hash
+		s.next = (s.next + 1) % len(s.order)
+	}
+	s.seen[hash] = struct{}{}
+	return true
+}
+
+// fetch gets the transactions of hashes. A nil transaction without error
+// means the transaction is gone.
+func (s *pendingStream) fetch(hashes []common.Hash) ([]*common.Transaction, []error) {
+	txs := make([]*common.Transaction, len(hashes))
+	errs := make([]error, len(hashes))
+
+	requests := make([]rpc.Request, len(hashes))
+	for i := range hashes {
+		requests[i] = s.eth.requestManager.newRequest("eth_getTransactionByHash")
+		requests[i].Set("params", hashes[i].String())
+	}
+
+	batch := s.eth.requestManager.canBatch()
+	size := 1
+	if batch {
+		size = s.option.BatchSize
+	}
+	starts := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < s.option.Concurrency && w*size < len(hashes); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range starts {
+				end := start + size
+				if end > len(hashes) {
+					end = len(hashes)
+				}
+				s.fetchChunk(requests[start:end], txs[start:end], errs[start:end], batch)
+			}
+		}()
+	}
+	for start := 0; start < len(hashes); start += size {
+		starts <- start
+	}
+	close(starts)
+	wg.Wait()
+	return txs, errs
+}
+
+// fetchChunk sends requests, in a batch if batch is true, and fills txs and
+// errs with their results.
+func (s *pendingStream) fetchChunk(requests []rpc.Request, txs []*common.Transaction, errs []error, batch bool) {
+	if !batch {
+		for i, request := range requests {
+			resp, err := s.eth.requestManager.send(request)
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+			txs[i], errs[i] = decodePendingTransaction(resp)
+		}
+		return
+	}
+
+	responses, err := s.eth.requestManager.sendBatch(requests)
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return
+	}
+	for i, resp := range responses {
+		txs[i], errs[i] = decodePendingTransaction(resp)
+	}
+}
+
+func (s *pendingStream) match(tx *common.Transaction) bool {
+	if !containsAddress(s.option.From, tx.From) || !containsAddress(s.option.To, tx.To) {
+		return false
+	}
+	if len(s.option.Selectors) == 0 {
+		return true
+	}
+	for _, selector := range s.option.Selectors {
+		if len(tx.Data) >= len(selector) && bytes.Equal(tx.Data[:len(selector)], selector) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAddress tells if addr is in addresses, an empty set contains any
+// address.
+func containsAddress(addresses []common.Address, addr common.Address) bool {
+	if len(addresses) == 0 {
+		return true
+	}
+	for _, a := range addresses {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func decodePendingTransaction(resp rpc.Response) (*common.Transaction, error) {
+	if resp.Error() != nil {
+		return nil, resp.Error()
+	}
+	result := resp.Get("result")
+	if result == nil {
+		return nil, nil
+	}
+
+	tx := &jsonTransaction{}
+	jsonBytes, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(jsonBytes, tx); err != nil {
+		return nil, err
+	}
+	return tx.ToTransaction(), nil
+}
+
+type transactionWatcher struct {
+	*typedWatch
+	ch chan *common.Transaction
+}
+
+func (w *transactionWatcher) Next() (*common.Transaction, error) {
+	v, err := w.next()
+	if err != nil {
+		return nil, err
+	}
+	return v.(*common.Transaction), nil
+}
+
+// Transactions returns a channel of transactions, closed when the watcher
+// stops.
+func (w *transactionWatcher) Transactions() <-chan *common.Transaction {
+	w.start()
+	return w.ch
+}
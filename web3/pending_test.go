@@ -0,0 +1,321 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alanchchen/web3go/common"
+	"github.com/alanchchen/web3go/provider"
+	"github.com/alanchchen/web3go/rpc"
+	"github.com/alanchchen/web3go/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// poolProvider simulates a transaction pool. Hashes added to the pool are
+// reported once by the pending transaction filter.
+type poolProvider struct {
+	provider.Provider
+
+	lock    sync.Mutex
+	txs     map[common.Hash]*common.Transaction
+	changes []string
+	fails   int
+	fetches int
+	// broken transactions always fail to be fetched.
+	broken map[common.Hash]bool
+}
+
+func newPoolProvider() *poolProvider {
+	return &poolProvider{
+		Provider: test.NewMockHTTPProvider(),
+		txs:      make(map[common.Hash]*common.Transaction),
+		broken:   make(map[common.Hash]bool),
+	}
+}
+
+// add reports a transaction from sender to recipient, calling a method
+// with selector. A transaction without sender is reported but is gone.
+func (p *poolProvider) add(id byte, from, to common.Address, selector []byte) common.Hash {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	hash := common.NewHash([]byte{id, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31})
+	if from != (common.Address{}) {
+		p.txs[hash] = &common.Transaction{
+			Hash:  hash,
+			From:  from,
+			To:    to,
+			Value: big.NewInt(int64(id)),
+			Data:  append(selector, 0, 0, 0, 1),
+		}
+	}
+	p.changes = append(p.changes, hash.String())
+	return hash
+}
+
+func (p *poolProvider) Send(request rpc.Request) (rpc.Response, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var result interface{}
+	switch request.Get("method").(string) {
+	case "eth_newPendingTransactionFilter":
+		result = "0x1"
+	case "eth_getFilterChanges":
+		result = p.changes
+		p.changes = []string{}
+	case "eth_getTransactionByHash":
+		p.fetches++
+		hash, _ := common.ParseHash(request.Get("params").([]interface{})[0].(string))
+		if p.fails > 0 || p.broken[hash] {
+			if !p.broken[hash] {
+				p.fails--
+			}
+			raw := fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"error":{"code":-32000,"message":"Busy"}}`, request.ID())
+			return p.GetRPCMethod().NewResponse([]byte(raw)), nil
+		}
+		if tx, ok := p.txs[hash]; ok {
+			result = test.NodeTransaction(tx)
+		}
+	default:
+		return p.Provider.Send(request)
+	}
+
+	data, _ := json.Marshal(result)
+	raw := fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":%s}`, request.ID(), data)
+	return p.GetRPCMethod().NewResponse([]byte(raw)), nil
+}
+
+// batchPoolProvider is a poolProvider able to send batches. Its counters
+// are guarded by the pool lock.
+type batchPoolProvider struct {
+	*poolProvider
+	batches int
+	largest int
+}
+
+func (p *batchPoolProvider) SendBatch(requests []rpc.Request) ([]rpc.Response, error) {
+	p.lock.Lock()
+	p.batches++
+	if len(requests) > p.largest {
+		p.largest = len(requests)
+	}
+	p.lock.Unlock()
+	responses := make([]rpc.Response, len(requests))
+	for i, request := range requests {
+		response, err := p.Send(request)
+		if err != nil {
+			return nil, err
+		}
+		responses[i] = response
+	}
+	return responses, nil
+}
+
+type PendingTestSuite struct {
+	suite.Suite
+	provider *poolProvider
+	watch    *WatchOption
+	alice    common.Address
+	bob      common.Address
+}
+
+func (suite *PendingTestSuite) watcher(p provider.Provider, option *PendingTransactionOption) TransactionWatcher {
+	if option == nil {
+		option = &PendingTransactionOption{}
+	}
+	option.Watch = suite.watch
+	watcher, err := NewWeb3(p).Eth.WatchPendingTransactionDetails(option)
+	assert.NoError(suite.T(), err, "Should be no error")
+	return watcher
+}
+
+func (suite *PendingTestSuite) expect(watcher TransactionWatcher, values ...int64) {
+	for _, value := range values {
+		select {
+		case tx := <-watcher.Transactions():
+			assert.Equal(suite.T(), big.NewInt(value), tx.Value, "Should be equal")
+		case err := <-watcher.Errors():
+			assert.NoError(suite.T(), err, "Should be no error")
+		case <-time.After(time.Second):
+			suite.T().Error("Should receive a transaction")
+		}
+	}
+}
+
+func (suite *PendingTestSuite) Test_Resolve() {
+	watcher := suite.watcher(suite.provider, nil)
+	defer watcher.Close()
+
+	hash := suite.provider.add(1, suite.alice, suite.bob, []byte{0xa9, 0x05, 0x9c, 0xbb})
+	tx, err := watcher.Next()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), hash, tx.Hash, "Should be equal")
+	assert.Equal(suite.T(), suite.alice, tx.From, "Should be equal")
+	assert.Equal(suite.T(), suite.bob, tx.To, "Should be equal")
+}
+
+func (suite *PendingTestSuite) Test_Batch() {
+	p := &batchPoolProvider{poolProvider: suite.provider}
+	watcher := suite.watcher(p, nil)
+	defer watcher.Close()
+
+	suite.provider.add(1, suite.alice, suite.bob, nil)
+	suite.provider.add(2, suite.alice, suite.bob, nil)
+	suite.expect(watcher, 1, 2)
+
+	suite.provider.lock.Lock()
+	defer suite.provider.lock.Unlock()
+	assert.Equal(suite.T(), 1, p.batches, "Should be equal")
+}
+
+func (suite *PendingTestSuite) Test_BatchSize() {
+	// The hashes are reported by the first poll.
+	for id := byte(1); id <= 5; id++ {
+		suite.provider.add(id, suite.alice, suite.bob, nil)
+	}
+	p := &batchPoolProvider{poolProvider: suite.provider}
+	watcher := suite.watcher(p, &PendingTransactionOption{BatchSize: 2, Concurrency: 2})
+	defer watcher.Close()
+	suite.expect(watcher, 1, 2, 3, 4, 5)
+
+	suite.provider.lock.Lock()
+	defer suite.provider.lock.Unlock()
+	assert.Equal(suite.T(), 3, p.batches, "Should be equal")
+	assert.Equal(suite.T(), 2, p.largest, "Should be equal")
+}
+
+func (suite *PendingTestSuite) Test_Filter() {
+	transfer := []byte{0xa9, 0x05, 0x9c, 0xbb}
+	watcher := suite.watcher(suite.provider, &PendingTransactionOption{
+		From:      []common.Address{suite.alice},
+		To:        []common.Address{suite.bob},
+		Selectors: [][]byte{transfer},
+	})
+	defer watcher.Close()
+
+	suite.provider.add(1, suite.bob, suite.bob, transfer)
+	suite.provider.add(2, suite.alice, suite.alice, transfer)
+	suite.provider.add(3, suite.alice, suite.bob, []byte{0x09, 0x5e, 0xa7, 0xb3})
+	suite.provider.add(4, suite.alice, suite.bob, transfer)
+	suite.expect(watcher, 4)
+}
+
+func (suite *PendingTestSuite) Test_Dedup() {
+	watcher := suite.watcher(suite.provider, &PendingTransactionOption{DedupSize: 2})
+	defer watcher.Close()
+
+	suite.provider.add(1, suite.alice, suite.bob, nil)
+	suite.provider.add(1, suite.alice, suite.bob, nil)
+	suite.provider.add(2, suite.alice, suite.bob, nil)
+	suite.expect(watcher, 1, 2)
+
+	// The third hash evicts the first one.
+	suite.provider.add(3, suite.alice, suite.bob, nil)
+	suite.provider.add(2, suite.alice, suite.bob, nil)
+	suite.provider.add(1, suite.alice, suite.bob, nil)
+	suite.expect(watcher, 3, 1)
+}
+
+func (suite *PendingTestSuite) Test_Disappeared() {
+	watcher := suite.watcher(suite.provider, nil)
+	defer watcher.Close()
+
+	suite.provider.add(1, common.Address{}, suite.bob, nil)
+	suite.provider.add(2, suite.alice, suite.bob, nil)
+	suite.expect(watcher, 2)
+}
+
+func (suite *PendingTestSuite) Test_Retry() {
+	suite.provider.fails = 2
+	watcher := suite.watcher(suite.provider, nil)
+	defer watcher.Close()
+
+	suite.provider.add(1, suite.alice, suite.bob, nil)
+	for errors := 0; ; errors++ {
+		tx, err := watcher.Next()
+		if err == nil {
+			assert.Equal(suite.T(), big.NewInt(1), tx.Value, "Should be equal")
+			assert.Equal(suite.T(), 2, errors, "Should be equal")
+			break
+		}
+		assert.EqualError(suite.T(), err, "Busy", "Should be equal")
+	}
+
+	suite.provider.lock.Lock()
+	defer suite.provider.lock.Unlock()
+	assert.Equal(suite.T(), 3, suite.provider.fetches, "Should be equal")
+}
+
+func (suite *PendingTestSuite) Test_GiveUp() {
+	filter, err := NewWeb3(suite.provider).Eth.NewPendingTransactionFilter()
+	assert.NoError(suite.T(), err, "Should be no error")
+	s := &pendingStream{
+		eth:    filter.(*baseFilter).eth,
+		option: PendingTransactionOption{Concurrency: 1, DedupSize: 8},
+		filter: filter.(*baseFilter),
+		seen:   make(map[common.Hash]struct{}),
+		retry:  make(map[common.Hash]int),
+	}
+
+	broken := suite.provider.add(1, suite.alice, suite.bob, nil)
+	suite.provider.broken[broken] = true
+	// Fetched twice already.
+	s.markSeen(broken)
+	s.retry[broken] = pendingFetchAttempts - 1
+	suite.provider.add(2, suite.alice, suite.bob, nil)
+
+	results, err := s.poll()
+	if assert.Len(suite.T(), results, 1, "Should keep the fetched transactions") {
+		assert.Equal(suite.T(), big.NewInt(2), results[0].(*common.Transaction).Value, "Should be equal")
+	}
+	if failed, ok := err.(*PendingFetchError); assert.True(suite.T(), ok, "Should be a PendingFetchError") {
+		assert.Equal(suite.T(), []common.Hash{broken}, failed.Hashes, "Should be equal")
+		assert.EqualError(suite.T(), failed.Err, "Busy", "Should be equal")
+	}
+	assert.Empty(suite.T(), s.retry, "Should not retry")
+}
+
+func (suite *PendingTestSuite) SetupTest() {
+	suite.provider = newPoolProvider()
+	suite.watch = &WatchOption{PollInterval: time.Millisecond}
+	suite.alice = common.StringToAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+	suite.bob = common.StringToAddress("0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359")
+}
+
+func Test_PendingTestSuite(t *testing.T) {
+	suite.Run(t, new(PendingTestSuite))
+}
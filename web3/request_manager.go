@@ -30,24 +30,108 @@
 package web3
 
 import (
+	"errors"
+	"sync"
+
 	"github.com/alanchchen/web3go/provider"
 	"github.com/alanchchen/web3go/rpc"
 )
 
+var (
+	ErrBatchNotSupported = errors.New("Provider does not support batch requests")
+)
+
+// poll is a background activity against the node, such as a filter being
+// watched, which Web3.Reset can stop.
+type poll interface {
+	// stop stops polling and releases node-side resources.
+	stop()
+	// syncing reports whether this is a syncing status poll.
+	syncing() bool
+}
+
 // requestManager is responsible for passing messages to providers
 type requestManager struct {
+	lock     sync.RWMutex
 	provider provider.Provider
 	rpc      rpc.RPC
+
+	pollsLock sync.Mutex
+	polls     map[poll]struct{}
 }
 
 func newRequestManager(provider provider.Provider) *requestManager {
-	return &requestManager{provider: provider, rpc: provider.GetRPCMethod()}
+	return &requestManager{
+		provider: provider,
+		rpc:      provider.GetRPCMethod(),
+		polls:    make(map[poll]struct{}),
+	}
+}
+
+func (rm *requestManager) getProvider() provider.Provider {
+	rm.lock.RLock()
+	defer rm.lock.RUnlock()
+	return rm.provider
+}
+
+// setProvider swaps the provider. Requests in flight complete against the old
+// provider, later requests go to the new one.
+func (rm *requestManager) setProvider(provider provider.Provider) {
+	rm.lock.Lock()
+	defer rm.lock.Unlock()
+	rm.provider = provider
+	rm.rpc = provider.GetRPCMethod()
 }
 
 func (rm *requestManager) newRequest(method string) rpc.Request {
+	rm.lock.RLock()
+	defer rm.lock.RUnlock()
 	return rm.rpc.NewRequest(method)
 }
 
 func (rm *requestManager) send(request rpc.Request) (rpc.Response, error) {
-	return rm.provider.Send(request)
+	return rm.getProvider().Send(request)
+}
+
+// canBatch tells if the provider can send batches.
+func (rm *requestManager) canBatch() bool {
+	_, ok := rm.getProvider().(provider.BatchProvider)
+	return ok
+}
+
+func (rm *requestManager) sendBatch(requests []rpc.Request) ([]rpc.Response, error) {
+	if batchProvider, ok := rm.getProvider().(provider.BatchProvider); ok {
+		return batchProvider.SendBatch(requests)
+	}
+	return nil, ErrBatchNotSupported
+}
+
+func (rm *requestManager) startPolling(p poll) {
+	rm.pollsLock.Lock()
+	defer rm.pollsLock.Unlock()
+	rm.polls[p] = struct{}{}
+}
+
+func (rm *requestManager) stopPolling(p poll) {
+	rm.pollsLock.Lock()
+	defer rm.pollsLock.Unlock()
+	delete(rm.polls, p)
+}
+
+// reset stops all polls, except syncing polls if keepSyncing is true.
+func (rm *requestManager) reset(keepSyncing bool) {
+	rm.pollsLock.Lock()
+	var polls []poll
+	for p := range rm.polls {
+		if keepSyncing && p.syncing() {
+			continue
+		}
+		polls = append(polls, p)
+		delete(rm.polls, p)
+	}
+	rm.pollsLock.Unlock()
+
+	for _, p := range polls {
+		p.stop()
+	}
 }
@@ -0,0 +1,173 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"context"
+	"math/big"
+	"sync"
+
+	"github.com/alanchchen/web3go/common"
+)
+
+// SyncEventType tells how the sync status changed.
+type SyncEventType int
+
+const (
+	// SyncStarted is reported when the node starts syncing.
+	SyncStarted SyncEventType = iota
+	// SyncProgress is reported when the current or highest block of a sync
+	// in progress changes.
+	SyncProgress
+	// SyncStopped is reported when the node is done syncing.
+	SyncStopped
+)
+
+// SyncEvent reports a change of the sync status.
+type SyncEvent struct {
+	Type   SyncEventType
+	Status common.SyncStatus
+}
+
+// SyncWatcher delivers sync status changes. The first event reports the
+// status at the first poll, SyncStarted if the node is syncing and
+// SyncStopped otherwise.
+type SyncWatcher interface {
+	Next() (*SyncEvent, error)
+	Close()
+}
+
+type syncWatcher struct {
+	eth      *EthAPI
+	wc       *watchChannel
+	stopOnce sync.Once
+
+	// last is only used by the polling goroutine.
+	last *common.SyncStatus
+}
+
+// WatchSyncing polls eth_syncing and reports changes of the sync status.
+// Web3.Reset keeps it running if asked to keep syncing polls.
+func (eth *EthAPI) WatchSyncing(watch *WatchOption) (SyncWatcher, error) {
+	w := &syncWatcher{eth: eth, wc: newWatchChannel(watch)}
+	eth.requestManager.startPolling(w)
+	go w.wc.run(w.poll)
+	return w, nil
+}
+
+// WaitSynced blocks until the node is not syncing, or ctx is done. Failed
+// polls are retried, it only fails if the watcher stops, e.g. with
+// ErrWatchOverflow.
+func (eth *EthAPI) WaitSynced(ctx context.Context, watch *WatchOption) error {
+	watcher, err := eth.WatchSyncing(watch)
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	return waitStopped(ctx, watcher)
+}
+
+// waitStopped receives the events of watcher until a SyncStopped event, an
+// error stopping the watcher, or ctx is done.
+func waitStopped(ctx context.Context, watcher SyncWatcher) error {
+	synced := make(chan error, 1)
+	go func() {
+		for ctx.Err() == nil {
+			event, err := watcher.Next()
+			if err == ErrChannelClosed || err == ErrWatchOverflow {
+				synced <- err
+				return
+			}
+			if err == nil && event.Type == SyncStopped {
+				synced <- nil
+				return
+			}
+		}
+	}()
+
+	select {
+	case err := <-synced:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *syncWatcher) poll() ([]interface{}, error) {
+	status, err := w.eth.Syncing()
+	if err != nil {
+		return nil, err
+	}
+
+	last := w.last
+	w.last = &status
+	switch {
+	case status.Result && (last == nil || !last.Result):
+		return []interface{}{&SyncEvent{Type: SyncStarted, Status: status}}, nil
+	case status.Result:
+		if !equalBlock(status.CurrentBlock, last.CurrentBlock) || !equalBlock(status.HighestBlock, last.HighestBlock) {
+			return []interface{}{&SyncEvent{Type: SyncProgress, Status: status}}, nil
+		}
+	case last == nil || last.Result:
+		return []interface{}{&SyncEvent{Type: SyncStopped, Status: status}}, nil
+	}
+	return nil, nil
+}
+
+func equalBlock(a, b *big.Int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Cmp(b) == 0
+}
+
+func (w *syncWatcher) Next() (*SyncEvent, error) {
+	data, err := w.wc.Next()
+	if err != nil {
+		return nil, err
+	}
+	return data.(*SyncEvent), nil
+}
+
+// Close stops polling.
+func (w *syncWatcher) Close() {
+	w.stop()
+}
+
+func (w *syncWatcher) stop() {
+	w.stopOnce.Do(func() {
+		w.wc.Close()
+		w.eth.requestManager.stopPolling(w)
+	})
+}
+
+func (w *syncWatcher) syncing() bool {
+	return true
+}
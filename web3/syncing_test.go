@@ -0,0 +1,230 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alanchchen/web3go/provider"
+	"github.com/alanchchen/web3go/rpc"
+	"github.com/alanchchen/web3go/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// syncProvider answers eth_syncing with a settable status.
+type syncProvider struct {
+	provider.Provider
+
+	lock   sync.Mutex
+	status interface{}
+}
+
+func (p *syncProvider) set(status interface{}) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.status = status
+}
+
+func (p *syncProvider) Send(request rpc.Request) (rpc.Response, error) {
+	if request.Get("method").(string) != "eth_syncing" {
+		return p.Provider.Send(request)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	data, _ := json.Marshal(p.status)
+	raw := fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":%s}`, request.ID(), data)
+	return p.GetRPCMethod().NewResponse([]byte(raw)), nil
+}
+
+func syncing(current, highest string) map[string]string {
+	return map[string]string{
+		"startingBlock": "0x384",
+		"currentBlock":  current,
+		"highestBlock":  highest,
+	}
+}
+
+// failedWatcher is a SyncWatcher returning the same error forever.
+type failedWatcher struct {
+	err error
+
+	lock  sync.Mutex
+	calls int
+}
+
+func (w *failedWatcher) Next() (*SyncEvent, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.calls++
+	return nil, w.err
+}
+
+func (w *failedWatcher) count() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.calls
+}
+
+func (w *failedWatcher) Close() {}
+
+type SyncingTestSuite struct {
+	suite.Suite
+	provider *syncProvider
+	web3     *Web3
+	watch    *WatchOption
+}
+
+func (suite *SyncingTestSuite) next(watcher SyncWatcher) *SyncEvent {
+	event, err := watcher.Next()
+	assert.NoError(suite.T(), err, "Should be no error")
+	return event
+}
+
+func (suite *SyncingTestSuite) Test_Syncing() {
+	suite.provider.set(syncing("0x386", "0x454"))
+	status, err := suite.web3.Eth.Syncing()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.True(suite.T(), status.Result, "Should be true")
+	assert.Equal(suite.T(), big.NewInt(0x384), status.StartingBlock, "Should be equal")
+	assert.Equal(suite.T(), big.NewInt(0x386), status.CurrentBlock, "Should be equal")
+	assert.Equal(suite.T(), big.NewInt(0x454), status.HighestBlock, "Should be equal")
+}
+
+func (suite *SyncingTestSuite) Test_WatchSyncing() {
+	suite.provider.set(syncing("0x386", "0x454"))
+	watcher, err := suite.web3.Eth.WatchSyncing(suite.watch)
+	assert.NoError(suite.T(), err, "Should be no error")
+	defer watcher.Close()
+
+	event := suite.next(watcher)
+	assert.Equal(suite.T(), SyncStarted, event.Type, "Should be equal")
+	assert.Equal(suite.T(), big.NewInt(0x386), event.Status.CurrentBlock, "Should be equal")
+
+	suite.provider.set(syncing("0x400", "0x454"))
+	event = suite.next(watcher)
+	assert.Equal(suite.T(), SyncProgress, event.Type, "Should be equal")
+	assert.Equal(suite.T(), big.NewInt(0x400), event.Status.CurrentBlock, "Should be equal")
+
+	suite.provider.set(false)
+	event = suite.next(watcher)
+	assert.Equal(suite.T(), SyncStopped, event.Type, "Should be equal")
+	assert.False(suite.T(), event.Status.Result, "Should be false")
+
+	suite.provider.set(syncing("0x454", "0x460"))
+	event = suite.next(watcher)
+	assert.Equal(suite.T(), SyncStarted, event.Type, "Should be equal")
+}
+
+func (suite *SyncingTestSuite) Test_NotSyncing() {
+	watcher, err := suite.web3.Eth.WatchSyncing(suite.watch)
+	assert.NoError(suite.T(), err, "Should be no error")
+	defer watcher.Close()
+
+	event := suite.next(watcher)
+	assert.Equal(suite.T(), SyncStopped, event.Type, "Should be equal")
+}
+
+func (suite *SyncingTestSuite) Test_WaitSynced() {
+	suite.provider.set(syncing("0x386", "0x454"))
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		suite.provider.set(false)
+	}()
+
+	err := suite.web3.Eth.WaitSynced(context.Background(), suite.watch)
+	assert.NoError(suite.T(), err, "Should be no error")
+}
+
+func (suite *SyncingTestSuite) Test_WaitSyncedCanceled() {
+	suite.provider.set(syncing("0x386", "0x454"))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := suite.web3.Eth.WaitSynced(ctx, suite.watch)
+	assert.Equal(suite.T(), context.DeadlineExceeded, err, "Should be equal")
+}
+
+func (suite *SyncingTestSuite) Test_WaitStoppedWatcher() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := waitStopped(ctx, &failedWatcher{err: ErrWatchOverflow})
+	assert.Equal(suite.T(), ErrWatchOverflow, err, "Should be equal")
+}
+
+func (suite *SyncingTestSuite) Test_WaitStoppedCanceled() {
+	watcher := &failedWatcher{err: fmt.Errorf("Connection refused")}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := waitStopped(ctx, watcher)
+	assert.Equal(suite.T(), context.DeadlineExceeded, err, "Should be equal")
+
+	// The receiving goroutine stops with ctx.
+	time.Sleep(10 * time.Millisecond)
+	calls := watcher.count()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(suite.T(), calls, watcher.count(), "Should stop receiving")
+}
+
+func (suite *SyncingTestSuite) Test_Reset() {
+	watcher, err := suite.web3.Eth.WatchSyncing(suite.watch)
+	assert.NoError(suite.T(), err, "Should be no error")
+	suite.next(watcher)
+
+	suite.web3.Reset(true)
+	suite.provider.set(syncing("0x386", "0x454"))
+	event := suite.next(watcher)
+	assert.Equal(suite.T(), SyncStarted, event.Type, "Should be equal")
+
+	suite.web3.Reset(false)
+	for {
+		if _, err := watcher.Next(); err != nil {
+			assert.Equal(suite.T(), ErrChannelClosed, err, "Should be equal")
+			break
+		}
+	}
+}
+
+func (suite *SyncingTestSuite) SetupTest() {
+	suite.provider = &syncProvider{Provider: test.NewMockHTTPProvider(), status: false}
+	suite.web3 = NewWeb3(suite.provider)
+	suite.watch = &WatchOption{PollInterval: time.Millisecond}
+}
+
+func Test_SyncingTestSuite(t *testing.T) {
+	suite.Run(t, new(SyncingTestSuite))
+}
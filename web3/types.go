@@ -36,76 +36,89 @@ import (
 	"github.com/alanchchen/web3go/common"
 )
 
+// jsonBlock is a block object as sent by nodes, with hex encoded fields.
+// common.Block holds the nonce, bloom and extra data in a hash, longer
+// values are truncated.
 type jsonBlock struct {
-	Number          json.Number    `json:"number"`
-	Hash            common.Hash    `json:"hash"`
-	ParentHash      common.Hash    `json:"parentHash"`
-	Nonce           common.Hash    `json:"nonce"`
-	Sha3Uncles      common.Hash    `json:"sha3Uncles"`
-	Bloom           common.Hash    `json:"logsBloom"`
-	TransactionRoot common.Hash    `json:"transactionsRoot"`
-	StateRoot       common.Hash    `json:"stateRoot"`
-	Miner           common.Address `json:"miner"`
-	Difficulty      json.Number    `json:"difficulty"`
-	TotalDifficulty json.Number    `json:"totalDifficulty"`
-	ExtraData       common.Hash    `json:"extraData"`
-	Size            json.Number    `json:"size"`
-	GasLimit        json.Number    `json:"gasLimit"`
-	GasUsed         json.Number    `json:"gasUsed"`
-	Timestamp       json.Number    `json:"timestamp"`
-	Transactions    []common.Hash  `json:"transactions"`
-	Uncles          []common.Hash  `json:"uncles"`
+	Number          interface{} `json:"number"`
+	Hash            hexHash     `json:"hash"`
+	ParentHash      hexHash     `json:"parentHash"`
+	Nonce           hexBytes    `json:"nonce"`
+	Sha3Uncles      hexHash     `json:"sha3Uncles"`
+	Bloom           hexBytes    `json:"logsBloom"`
+	TransactionRoot hexHash     `json:"transactionsRoot"`
+	StateRoot       hexHash     `json:"stateRoot"`
+	Miner           hexAddress  `json:"miner"`
+	Difficulty      interface{} `json:"difficulty"`
+	TotalDifficulty interface{} `json:"totalDifficulty"`
+	ExtraData       hexBytes    `json:"extraData"`
+	Size            interface{} `json:"size"`
+	GasLimit        interface{} `json:"gasLimit"`
+	GasUsed         interface{} `json:"gasUsed"`
+	Timestamp       interface{} `json:"timestamp"`
+	Transactions    []hexTxHash `json:"transactions"`
+	Uncles          []hexHash   `json:"uncles"`
 }
 
 func (b *jsonBlock) ToBlock() (block *common.Block) {
 	block = &common.Block{}
-	block.Number = jsonNumbertoInt(b.Number)
-	block.Hash = b.Hash
-	block.ParentHash = b.ParentHash
-	block.Nonce = b.Nonce
-	block.Sha3Uncles = b.Sha3Uncles
-	block.Bloom = b.Bloom
-	block.TransactionRoot = b.TransactionRoot
-	block.StateRoot = b.StateRoot
-	block.Miner = b.Miner
-	block.Difficulty = jsonNumbertoInt(b.Difficulty)
-	block.TotalDifficulty = jsonNumbertoInt(b.TotalDifficulty)
-	block.ExtraData = b.ExtraData
-	block.Size = jsonNumbertoInt(b.Size)
-	block.GasLimit = jsonNumbertoInt(b.GasLimit)
-	block.GasUsed = jsonNumbertoInt(b.GasUsed)
-	block.Timestamp = jsonNumbertoInt(b.Timestamp)
-	block.Transactions = b.Transactions
-	block.Uncles = b.Uncles
+	block.Number = rawQuantity(b.Number)
+	block.Hash = common.Hash(b.Hash)
+	block.ParentHash = common.Hash(b.ParentHash)
+	block.Nonce = common.NewHash(b.Nonce)
+	block.Sha3Uncles = common.Hash(b.Sha3Uncles)
+	block.Bloom = common.NewHash(b.Bloom)
+	block.TransactionRoot = common.Hash(b.TransactionRoot)
+	block.StateRoot = common.Hash(b.StateRoot)
+	block.Miner = common.Address(b.Miner)
+	block.Difficulty = rawQuantity(b.Difficulty)
+	block.TotalDifficulty = rawQuantity(b.TotalDifficulty)
+	block.ExtraData = common.NewHash(b.ExtraData)
+	block.Size = rawQuantity(b.Size)
+	block.GasLimit = rawQuantity(b.GasLimit)
+	block.GasUsed = rawQuantity(b.GasUsed)
+	block.Timestamp = rawQuantity(b.Timestamp)
+	block.Transactions = make([]common.Hash, 0, len(b.Transactions))
+	for _, hash := range b.Transactions {
+		block.Transactions = append(block.Transactions, common.Hash(hash))
+	}
+	block.Uncles = make([]common.Hash, 0, len(b.Uncles))
+	for _, hash := range b.Uncles {
+		block.Uncles = append(block.Uncles, common.Hash(hash))
+	}
 	return block
 }
 
+// jsonTransaction is a transaction object as sent by nodes, with hex encoded
+// fields. The block fields are null for pending transactions.
 type jsonTransaction struct {
-	Hash             common.Hash    `json:"hash"`
-	Nonce            common.Hash    `json:"nonce"`
-	BlockHash        common.Hash    `json:"blockHash"`
-	BlockNumber      json.Number    `json:"blockNumber"`
-	TransactionIndex uint64         `json:"transactionIndex"`
-	From             common.Address `json:"from"`
-	To               common.Address `json:"to"`
-	Gas              json.Number    `json:"gas"`
-	GasPrice         json.Number    `json:"gasprice"`
-	Value            json.Number    `json:"value"`
-	Data             []byte         `json:"input"`
+	Hash             hexHash     `json:"hash"`
+	Nonce            interface{} `json:"nonce"`
+	BlockHash        hexHash     `json:"blockHash"`
+	BlockNumber      interface{} `json:"blockNumber"`
+	TransactionIndex interface{} `json:"transactionIndex"`
+	From             hexAddress  `json:"from"`
+	To               hexAddress  `json:"to"`
+	Gas              interface{} `json:"gas"`
+	GasPrice         interface{} `json:"gasPrice"`
+	Value            interface{} `json:"value"`
+	Data             hexBytes    `json:"input"`
 }
 
 func (t *jsonTransaction) ToTransaction() (tx *common.Transaction) {
 	tx = &common.Transaction{}
-	tx.Hash = t.Hash
-	tx.Nonce = t.Nonce
-	tx.BlockHash = t.BlockHash
-	tx.BlockNumber = jsonNumbertoInt(t.BlockNumber)
-	tx.TransactionIndex = t.TransactionIndex
-	tx.From = t.From
-	tx.To = t.To
-	tx.Gas = jsonNumbertoInt(t.Gas)
-	tx.GasPrice = jsonNumbertoInt(t.GasPrice)
-	tx.Value = jsonNumbertoInt(t.Value)
+	tx.Hash = common.Hash(t.Hash)
+	if nonce := rawQuantity(t.Nonce); nonce != nil {
+		nonce.FillBytes(tx.Nonce[:])
+	}
+	tx.BlockHash = common.Hash(t.BlockHash)
+	tx.BlockNumber = rawQuantity(t.BlockNumber)
+	tx.TransactionIndex = rawUint64(t.TransactionIndex)
+	tx.From = common.Address(t.From)
+	tx.To = common.Address(t.To)
+	tx.Gas = rawQuantity(t.Gas)
+	tx.GasPrice = rawQuantity(t.GasPrice)
+	tx.Value = rawQuantity(t.Value)
 	tx.Data = t.Data
 	return tx
 }
@@ -137,30 +150,141 @@ func (r *jsonTransactionReceipt) ToTransactionReceipt() (receipt *common.Transac
 	return receipt
 }
 
+// jsonLog is a log object as sent by nodes, with hex encoded fields.
 type jsonLog struct {
-	LogIndex         uint64         `json:"logIndex"`
-	BlockNumber      json.Number    `json:"blockNumber"`
-	BlockHash        common.Hash    `json:"blockHash"`
-	TransactionHash  common.Hash    `json:"transactionHash"`
-	TransactionIndex uint64         `json:"transactionIndex"`
-	Address          common.Address `json:"address"`
-	Data             []byte         `json:"data"`
-	Topics           common.Topics  `json:"topics"`
+	LogIndex         interface{} `json:"logIndex"`
+	BlockNumber      interface{} `json:"blockNumber"`
+	BlockHash        hexHash     `json:"blockHash"`
+	TransactionHash  hexHash     `json:"transactionHash"`
+	TransactionIndex interface{} `json:"transactionIndex"`
+	Address          hexAddress  `json:"address"`
+	Data             hexBytes    `json:"data"`
+	Topics           []hexBytes  `json:"topics"`
+	Removed          bool        `json:"removed"`
 }
 
 func (l jsonLog) ToLog() (log common.Log) {
 	log = common.Log{}
-	log.LogIndex = l.LogIndex
-	log.BlockNumber = jsonNumbertoInt(l.BlockNumber)
-	log.BlockHash = l.BlockHash
-	log.TransactionHash = l.TransactionHash
-	log.TransactionIndex = l.TransactionIndex
-	log.Address = l.Address
+	log.LogIndex = rawUint64(l.LogIndex)
+	log.BlockNumber = rawQuantity(l.BlockNumber)
+	log.BlockHash = common.Hash(l.BlockHash)
+	log.TransactionHash = common.Hash(l.TransactionHash)
+	log.TransactionIndex = rawUint64(l.TransactionIndex)
+	log.Address = common.Address(l.Address)
 	log.Data = l.Data
-	log.Topics = l.Topics
+	log.Topics = make(common.Topics, 0, len(l.Topics))
+	for _, topic := range l.Topics {
+		log.Topics = append(log.Topics, common.Topic{Data: topic})
+	}
+	log.Removed = l.Removed
 	return log
 }
 
+func toLogs(results []interface{}) ([]common.Log, error) {
+	jsonLogs := make([]jsonLog, 0, len(results))
+	jsonBytes, err := json.Marshal(results)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(jsonBytes, &jsonLogs); err != nil {
+		return nil, err
+	}
+
+	logs := make([]common.Log, 0, len(jsonLogs))
+	for _, l := range jsonLogs {
+		logs = append(logs, l.ToLog())
+	}
+	return logs, nil
+}
+
+// jsonSyncStatus is the sync progress object of eth_syncing. Nodes send hex
+// quantities, which json.Number can't hold.
+type jsonSyncStatus struct {
+	StartingBlock interface{} `json:"startingBlock"`
+	CurrentBlock  interface{} `json:"currentBlock"`
+	HighestBlock  interface{} `json:"highestBlock"`
+}
+
+func (s *jsonSyncStatus) ToSyncStatus() common.SyncStatus {
+	return common.SyncStatus{
+		Result:        true,
+		StartingBlock: rawQuantity(s.StartingBlock),
+		CurrentBlock:  rawQuantity(s.CurrentBlock),
+		HighestBlock:  rawQuantity(s.HighestBlock),
+	}
+}
+
+// hexHash, hexAddress and hexBytes decode the 0x prefixed hex strings sent by
+// nodes. null decodes to the zero value.
+type hexHash common.Hash
+
+func (h *hexHash) UnmarshalJSON(data []byte) error {
+	s, err := unmarshalHex(data)
+	if err != nil || s == "" {
+		return err
+	}
+	hash, err := common.ParseHash(s)
+	*h = hexHash(hash)
+	return err
+}
+
+// hexTxHash is a transaction hash, or the hash of a transaction object when
+// a block is fetched with full transactions.
+type hexTxHash common.Hash
+
+func (h *hexTxHash) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		tx := struct {
+			Hash hexHash `json:"hash"`
+		}{}
+		if err := json.Unmarshal(data, &tx); err != nil {
+			return err
+		}
+		*h = hexTxHash(tx.Hash)
+		return nil
+	}
+	return (*hexHash)(h).UnmarshalJSON(data)
+}
+
+type hexAddress common.Address
+
+func (a *hexAddress) UnmarshalJSON(data []byte) error {
+	s, err := unmarshalHex(data)
+	if err != nil || s == "" {
+		return err
+	}
+	addr, err := common.ParseAddress(s)
+	*a = hexAddress(addr)
+	return err
+}
+
+type hexBytes []byte
+
+func (b *hexBytes) UnmarshalJSON(data []byte) error {
+	s, err := unmarshalHex(data)
+	if err != nil || s == "" {
+		return err
+	}
+	*b, err = common.DecodeHex(s)
+	return err
+}
+
+// unmarshalHex decodes a JSON string, or null as an empty string.
+func unmarshalHex(data []byte) (string, error) {
+	var s string
+	err := json.Unmarshal(data, &s)
+	return s, err
+}
+
+// rawUint64 is like rawQuantity for quantities known to fit 64 bits, such as
+// indexes. Missing values are 0.
+func rawUint64(value interface{}) uint64 {
+	if result := rawQuantity(value); result != nil {
+		return result.Uint64()
+	}
+	return 0
+}
+
 func jsonNumbertoInt(data json.Number) *big.Int {
 	f := big.NewFloat(0.0)
 	f.SetString(string(data))
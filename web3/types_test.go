@@ -0,0 +1,169 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/alanchchen/web3go/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// Objects as sent by geth.
+const (
+	nodeLog = `{
+		"address": "0x16c5785ac562ff41e2dcfdf829c5a142f1fccd7d",
+		"topics": ["0x59ebeb90bc63057b6515673c3ecf9438e5058bca0f92585014eced636878c9a5"],
+		"data": "0x0000000000000000000000000000000000000000000000000000000000000001",
+		"blockNumber": "0x1b4",
+		"transactionHash": "0xdf829c5a142f1fccd7d8216c5785ac562ff41e2dcfdf5785ac562ff41e2dcf0a",
+		"transactionIndex": "0x2",
+		"blockHash": "0x8216c5785ac562ff41e2dcfdf5785ac562ff41e2dcfdf829c5a142f1fccd7d00",
+		"logIndex": "0x1a",
+		"removed": false
+	}`
+	nodePendingTransaction = `{
+		"blockHash": null,
+		"blockNumber": null,
+		"from": "0x407d73d8a49eeb85d32cf465507dd71d507100c1",
+		"gas": "0x5208",
+		"gasPrice": "0x9184e72a000",
+		"hash": "0xc6ef2fc5426d6ad6fd9e2a26abeab0aa2411b7ab17f30a99d3cb96aed1d1055b",
+		"input": "0xa9059cbb",
+		"nonce": "0x15",
+		"to": null,
+		"transactionIndex": null,
+		"value": "0xde0b6b3a7640000",
+		"v": "0x25",
+		"r": "0x1b5e176d927f8e9ab405058b2d2457392da3e20f328b16ddabcebc33eaac5fea",
+		"s": "0x4ba69724e8f69de52f0125ad8b3c5c2cef33019bac3249e2c0a2192766d1721c"
+	}`
+)
+
+// nodeBlock is a block as sent by geth, its transactions are filled in with
+// either hashes or objects.
+var nodeBlock = `{
+	"number": "0x1b4",
+	"hash": "0xdc0818cf78f21a8e70579cb46a43643f78291264dda342ae31049421c82d21ae",
+	"parentHash": "0xe99e022112df268087ea7eafaf4790497fd21dbeeb6bd7a1721df161a6657a54",
+	"nonce": "0x689056015818adbe",
+	"sha3Uncles": "0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347",
+	"logsBloom": "0x` + strings.Repeat("00", 256) + `",
+	"transactionsRoot": "0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421",
+	"stateRoot": "0xddc8b0234c2e0cad087c8b389aa7ef01f7d79b2570bccb77ce48648aa61c904d",
+	"receiptsRoot": "0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421",
+	"miner": "0xbb7b8287f3f0a933474a79eae42cbca977791171",
+	"difficulty": "0x4ea3f27bc",
+	"totalDifficulty": "0x78ed983323d",
+	"extraData": "0x476574682f4c5649562f76312e302e302f6c696e75782f676f312e342e32",
+	"size": "0x220",
+	"gasLimit": "0x1388",
+	"gasUsed": "0x0",
+	"timestamp": "0x55ba467c",
+	"transactions": [%s],
+	"uncles": []
+}`
+
+type TypesTestSuite struct {
+	suite.Suite
+}
+
+func (suite *TypesTestSuite) Test_Log() {
+	var raw interface{}
+	assert.NoError(suite.T(), json.Unmarshal([]byte(nodeLog), &raw), "Should be no error")
+
+	logs, err := toLogs([]interface{}{raw})
+	assert.NoError(suite.T(), err, "Should be no error")
+	if assert.Len(suite.T(), logs, 1, "Should be equal") {
+		log := logs[0]
+		assert.Equal(suite.T(), uint64(0x1a), log.LogIndex, "Should be equal")
+		assert.Equal(suite.T(), big.NewInt(0x1b4), log.BlockNumber, "Should be equal")
+		assert.Equal(suite.T(), common.StringToHash("0x8216c5785ac562ff41e2dcfdf5785ac562ff41e2dcfdf829c5a142f1fccd7d00"), log.BlockHash, "Should be equal")
+		assert.Equal(suite.T(), common.StringToHash("0xdf829c5a142f1fccd7d8216c5785ac562ff41e2dcfdf5785ac562ff41e2dcf0a"), log.TransactionHash, "Should be equal")
+		assert.Equal(suite.T(), uint64(2), log.TransactionIndex, "Should be equal")
+		assert.Equal(suite.T(), common.StringToAddress("0x16c5785ac562ff41e2dcfdf829c5a142f1fccd7d"), log.Address, "Should be equal")
+		assert.Equal(suite.T(), append(make([]byte, 31), 1), log.Data, "Should be equal")
+		if assert.Len(suite.T(), log.Topics, 1, "Should be equal") {
+			assert.Equal(suite.T(), common.HexToBytes("0x59ebeb90bc63057b6515673c3ecf9438e5058bca0f92585014eced636878c9a5"), log.Topics[0].Data, "Should be equal")
+		}
+	}
+
+	_, err = toLogs([]interface{}{map[string]interface{}{"address": "0x16c5"}})
+	assert.Error(suite.T(), err, "Should be error")
+	_, err = toLogs([]interface{}{map[string]interface{}{"data": "0xzz"}})
+	assert.Error(suite.T(), err, "Should be error")
+}
+
+func (suite *TypesTestSuite) Test_PendingTransaction() {
+	result := &jsonTransaction{}
+	assert.NoError(suite.T(), json.Unmarshal([]byte(nodePendingTransaction), result), "Should be no error")
+
+	tx := result.ToTransaction()
+	assert.Equal(suite.T(), common.StringToHash("0xc6ef2fc5426d6ad6fd9e2a26abeab0aa2411b7ab17f30a99d3cb96aed1d1055b"), tx.Hash, "Should be equal")
+	assert.Equal(suite.T(), byte(0x15), tx.Nonce[31], "Should be equal")
+	assert.Equal(suite.T(), common.Hash{}, tx.BlockHash, "Should be equal")
+	assert.Nil(suite.T(), tx.BlockNumber, "Should be nil")
+	assert.Equal(suite.T(), common.StringToAddress("0x407d73d8a49eeb85d32cf465507dd71d507100c1"), tx.From, "Should be equal")
+	assert.Equal(suite.T(), common.Address{}, tx.To, "Should be equal")
+	assert.Equal(suite.T(), big.NewInt(21000), tx.Gas, "Should be equal")
+	assert.Equal(suite.T(), big.NewInt(0x9184e72a000), tx.GasPrice, "Should be equal")
+	assert.Equal(suite.T(), "1000000000000000000", tx.Value.String(), "Should be equal")
+	assert.Equal(suite.T(), []byte{0xa9, 0x05, 0x9c, 0xbb}, tx.Data, "Should be equal")
+}
+
+func (suite *TypesTestSuite) Test_Block() {
+	txHash := "0xc6ef2fc5426d6ad6fd9e2a26abeab0aa2411b7ab17f30a99d3cb96aed1d1055b"
+	for _, txs := range []string{`"` + txHash + `"`, nodePendingTransaction} {
+		result := &jsonBlock{}
+		data := strings.Replace(nodeBlock, "%s", txs, 1)
+		if !assert.NoError(suite.T(), json.Unmarshal([]byte(data), result), "Should be no error") {
+			continue
+		}
+
+		block := result.ToBlock()
+		assert.Equal(suite.T(), big.NewInt(0x1b4), block.Number, "Should be equal")
+		assert.Equal(suite.T(), common.StringToHash("0xdc0818cf78f21a8e70579cb46a43643f78291264dda342ae31049421c82d21ae"), block.Hash, "Should be equal")
+		assert.Equal(suite.T(), common.StringToHash("0xe99e022112df268087ea7eafaf4790497fd21dbeeb6bd7a1721df161a6657a54"), block.ParentHash, "Should be equal")
+		assert.Equal(suite.T(), common.HexToBytes("0x689056015818adbe"), block.Nonce[:8], "Should be equal")
+		assert.Equal(suite.T(), common.StringToAddress("0xbb7b8287f3f0a933474a79eae42cbca977791171"), block.Miner, "Should be equal")
+		assert.Equal(suite.T(), big.NewInt(0x78ed983323d), block.TotalDifficulty, "Should be equal")
+		assert.Equal(suite.T(), big.NewInt(0x55ba467c), block.Timestamp, "Should be equal")
+		assert.Equal(suite.T(), []common.Hash{common.StringToHash(txHash)}, block.Transactions, "Should be equal")
+		assert.Empty(suite.T(), block.Uncles, "Should be empty")
+	}
+}
+
+func Test_TypesTestSuite(t *testing.T) {
+	suite.Run(t, new(TypesTestSuite))
+}
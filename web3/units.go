@@ -0,0 +1,221 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrInvalidAmount  = errors.New("Invalid amount")
+	ErrTooManyDigits  = errors.New("Amount has more fractional digits than the unit allows")
+	ErrNegativeDigits = errors.New("Negative number of decimals")
+
+	// decimalPattern is the accepted syntax of decimal amounts.
+	decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+	// hexPattern is the accepted syntax of hex amounts in ToWei and FromWei.
+	hexPattern = regexp.MustCompile(`^-?0x[0-9a-fA-F]+$`)
+)
+
+// FormatOption controls how FormatAmount and FormatUnit render a value. The
+// exact value is printed unless Round is set, a nil option prints it without
+// separators.
+type FormatOption struct {
+	// Round rounds the fraction to Precision digits.
+	Round bool
+	// Precision is the number of fractional digits rounded to, and padded
+	// to with Fixed.
+	Precision int
+	// Fixed pads the fraction with zeros up to Precision.
+	Fixed bool
+	// Thousands separates groups of three digits in the integer part, e.g. ",".
+	Thousands string
+}
+
+// UnitDecimals returns the power of ten of the given ether unit relative to
+// wei, e.g. 18 for "ether". An empty unit means "ether".
+func UnitDecimals(unit string) (int, error) {
+	u := strings.ToLower(strings.TrimSpace(unit))
+	if u == "" {
+		u = "ether"
+	}
+
+	unitValue, ok := unitMap[u]
+	if !ok || u == "noether" {
+		keys := make([]string, 0, len(unitMap))
+		for k := range unitMap {
+			if k != "noether" && k == strings.ToLower(k) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		return 0, fmt.Errorf("Unknown unit %q, please use one of %v", unit, keys)
+	}
+	return len(unitValue) - 1, nil
+}
+
+// ParseAmount converts a decimal string such as "1.5" into an integer amount
+// of the smallest denomination, given the number of decimals of the currency
+// (18 for ether, or an ERC-20 token's decimals()). The conversion is exact: it
+// fails if the amount has more fractional digits than decimals. Only plain
+// decimal notation is accepted, no hex, exponents or digit separators.
+func ParseAmount(amount string, decimals int) (*big.Int, error) {
+	if decimals < 0 {
+		return nil, ErrNegativeDigits
+	}
+	value := parseDecimal(strings.TrimSpace(amount))
+	if value == nil {
+		return nil, ErrInvalidAmount
+	}
+	return ratToAmount(value, decimals)
+}
+
+// parseDecimal parses a plain decimal number, returning nil for any other
+// syntax.
+func parseDecimal(s string) *big.Rat {
+	if !decimalPattern.MatchString(s) {
+		return nil
+	}
+	value, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil
+	}
+	return value
+}
+
+// FormatAmount converts an integer amount of the smallest denomination into a
+// decimal string, given the number of decimals of the currency.
+func FormatAmount(value *big.Int, decimals int, option *FormatOption) string {
+	if value == nil {
+		value = big0
+	}
+	if decimals < 0 {
+		decimals = 0
+	}
+	if option == nil {
+		option = &FormatOption{}
+	}
+
+	r := new(big.Rat).SetFrac(value, pow10(decimals))
+	precision := decimals
+	if option.Round && option.Precision >= 0 && option.Precision < decimals {
+		precision = option.Precision
+	}
+	s := r.FloatString(precision)
+
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	integer, fraction := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		integer, fraction = s[:i], s[i+1:]
+	}
+
+	fraction = strings.TrimRight(fraction, "0")
+	if option.Fixed && option.Precision > len(fraction) {
+		fraction += strings.Repeat("0", option.Precision-len(fraction))
+	}
+	if strings.Trim(integer+fraction, "0") == "" {
+		sign = ""
+	}
+
+	result := sign + groupThousands(integer, option.Thousands)
+	if fraction != "" {
+		result += "." + fraction
+	}
+	return result
+}
+
+// ParseUnit converts a decimal amount of the given ether unit into wei, e.g.
+// ParseUnit("1.5", "ether").
+func ParseUnit(amount string, unit string) (*big.Int, error) {
+	if isNoether(unit) {
+		if _, err := ParseAmount(amount, 0); err != nil && err != ErrTooManyDigits {
+			return nil, err
+		}
+		return new(big.Int), nil
+	}
+	decimals, err := UnitDecimals(unit)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAmount(amount, decimals)
+}
+
+// FormatUnit converts an amount of wei into a decimal string in the given
+// ether unit, e.g. FormatUnit(wei, "gwei", nil).
+func FormatUnit(wei *big.Int, unit string, option *FormatOption) (string, error) {
+	decimals, err := UnitDecimals(unit)
+	if err != nil {
+		return "", err
+	}
+	return FormatAmount(wei, decimals, option), nil
+}
+
+func ratToAmount(value *big.Rat, decimals int) (*big.Int, error) {
+	scaled := new(big.Rat).Mul(value, new(big.Rat).SetInt(pow10(decimals)))
+	if !scaled.IsInt() {
+		return nil, ErrTooManyDigits
+	}
+	return new(big.Int).Set(scaled.Num()), nil
+}
+
+func isNoether(unit string) bool {
+	return strings.ToLower(strings.TrimSpace(unit)) == "noether"
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func groupThousands(integer string, separator string) string {
+	if separator == "" || len(integer) <= 3 {
+		return integer
+	}
+	var buffer bytes.Buffer
+	head := len(integer) % 3
+	if head > 0 {
+		buffer.WriteString(integer[:head])
+	}
+	for i := head; i < len(integer); i += 3 {
+		if buffer.Len() > 0 {
+			buffer.WriteString(separator)
+		}
+		buffer.WriteString(integer[i : i+3])
+	}
+	return buffer.String()
+}
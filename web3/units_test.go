@@ -0,0 +1,140 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type UnitsTestSuite struct {
+	suite.Suite
+}
+
+func (suite *UnitsTestSuite) Test_UnitDecimals() {
+	decimals, err := UnitDecimals("ether")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), 18, decimals, "Should be equal")
+
+	decimals, err = UnitDecimals("Gwei")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), 9, decimals, "Should be equal")
+
+	_, err = UnitDecimals("dogecoin")
+	assert.Error(suite.T(), err, "Should be error")
+}
+
+func (suite *UnitsTestSuite) Test_ParseUnit() {
+	wei, err := ParseUnit("1.5", "ether")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "1500000000000000000", wei.String(), "Should be equal")
+
+	_, err = ParseUnit("0.0000000001", "gwei")
+	assert.Equal(suite.T(), ErrTooManyDigits, err, "Should be equal")
+
+	wei, err = ParseUnit("-2.25", "gwei")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "-2250000000", wei.String(), "Should be equal")
+
+	wei, err = ParseUnit("100", "noether")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), 0, wei.Int64(), "Should be equal")
+
+	_, err = ParseUnit("1.5", "unknown")
+	assert.Error(suite.T(), err, "Should be error")
+	_, err = ParseUnit("one", "ether")
+	assert.Equal(suite.T(), ErrInvalidAmount, err, "Should be equal")
+	_, err = ParseUnit("1/3", "ether")
+	assert.Equal(suite.T(), ErrInvalidAmount, err, "Should be equal")
+}
+
+func (suite *UnitsTestSuite) Test_ParseAmount() {
+	amount, err := ParseAmount("12.345678", 6)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), big.NewInt(12345678), amount, "Should be equal")
+
+	_, err = ParseAmount("1.0000001", 6)
+	assert.Equal(suite.T(), ErrTooManyDigits, err, "Should be equal")
+	_, err = ParseAmount("1", -1)
+	assert.Equal(suite.T(), ErrNegativeDigits, err, "Should be equal")
+
+	amount, err = ParseAmount(" -0.5 ", 1)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), big.NewInt(-5), amount, "Should be equal")
+	for _, malformed := range []string{"0x10", "0b11", "0o7", "1_000", "1e3", "1.", ".5", "+1", "1/3", ""} {
+		_, err = ParseAmount(malformed, 18)
+		assert.Equal(suite.T(), ErrInvalidAmount, err, "Should reject "+malformed)
+	}
+}
+
+func (suite *UnitsTestSuite) Test_FormatUnit() {
+	wei, _ := new(big.Int).SetString("1234567891500000000000000", 10)
+
+	s, err := FormatUnit(wei, "ether", nil)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "1234567.8915", s, "Should be equal")
+
+	s, err = FormatUnit(wei, "ether", &FormatOption{Round: true, Precision: 2, Thousands: ","})
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "1,234,567.89", s, "Should be equal")
+
+	s, err = FormatUnit(wei, "ether", &FormatOption{Thousands: ","})
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "1,234,567.8915", s, "Separators alone should keep the exact value")
+
+	s, err = FormatUnit(wei, "ether", &FormatOption{Precision: 6, Fixed: true, Thousands: " "})
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "1 234 567.891500", s, "Should be equal")
+
+	s, err = FormatUnit(wei, "ether", &FormatOption{Round: true})
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "1234568", s, "Should be equal")
+
+	s, err = FormatUnit(big.NewInt(-1), "gwei", &FormatOption{Round: true, Precision: 3})
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "0", s, "Should be equal")
+
+	_, err = FormatUnit(wei, "noether", nil)
+	assert.Error(suite.T(), err, "Should be error")
+}
+
+func (suite *UnitsTestSuite) Test_FormatAmount() {
+	assert.EqualValues(suite.T(), "-0.5", FormatAmount(big.NewInt(-500000), 6, nil), "Should be equal")
+	assert.EqualValues(suite.T(), "100", FormatAmount(big.NewInt(100), 0, nil), "Should be equal")
+	assert.EqualValues(suite.T(), "0", FormatAmount(nil, 18, nil), "Should be equal")
+	assert.EqualValues(suite.T(), "1,234,567.891", FormatAmount(big.NewInt(1234567891), 3, &FormatOption{Thousands: ","}), "Should be equal")
+}
+
+func Test_UnitsTestSuite(t *testing.T) {
+	suite.Run(t, new(UnitsTestSuite))
+}
@@ -0,0 +1,278 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrChannelClosed = errors.New("Channel is closed")
+	ErrWatchOverflow = errors.New("Watch buffer is full")
+)
+
+const (
+	pollInterval   = 100 * time.Millisecond
+	dataBufferSize = 16
+	maxPollBackoff = 30 * time.Second
+)
+
+// BackpressurePolicy decides what a watcher does when its consumer falls
+// behind and the buffer is full.
+type BackpressurePolicy int
+
+const (
+	// BackpressureBlock stops polling until the consumer catches up.
+	BackpressureBlock BackpressurePolicy = iota
+	// BackpressureDropOldest discards the oldest buffered item.
+	BackpressureDropOldest
+	// BackpressureError stops the watcher, Next returns ErrWatchOverflow once
+	// the buffer is drained.
+	BackpressureError
+)
+
+// WatchOption configures how a filter is polled. Zero fields take defaults.
+type WatchOption struct {
+	// PollInterval is the delay between two polls, 100ms by default.
+	PollInterval time.Duration
+	// BufferSize is the number of items buffered for the consumer, 16 by
+	// default.
+	BufferSize int
+	// Backpressure is the policy applied when the buffer is full.
+	Backpressure BackpressurePolicy
+	// MaxBackoff caps the exponential backoff applied while polls fail, 30s
+	// by default.
+	MaxBackoff time.Duration
+}
+
+func (opt *WatchOption) withDefaults() WatchOption {
+	result := WatchOption{}
+	if opt != nil {
+		result = *opt
+	}
+	if result.PollInterval <= 0 {
+		result.PollInterval = pollInterval
+	}
+	if result.BufferSize <= 0 {
+		result.BufferSize = dataBufferSize
+	}
+	if result.MaxBackoff <= 0 {
+		result.MaxBackoff = maxPollBackoff
+	}
+	if result.MaxBackoff < result.PollInterval {
+		result.MaxBackoff = result.PollInterval
+	}
+	return result
+}
+
+// backoff returns the delay before the next poll after the given number of
+// consecutive failures.
+func (opt *WatchOption) backoff(failures uint) time.Duration {
+	delay := opt.PollInterval
+	for i := uint(0); i < failures && delay < opt.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > opt.MaxBackoff {
+		delay = opt.MaxBackoff
+	}
+	return delay
+}
+
+// WatchChannel ...
+type WatchChannel interface {
+	Next() (interface{}, error)
+	Close()
+}
+
+type watchResult struct {
+	data interface{}
+	err  error
+}
+
+type watchChannel struct {
+	option    WatchOption
+	dataCh    chan watchResult
+	closeCh   chan struct{}
+	closeOnce sync.Once
+
+	lock sync.Mutex
+	err  error
+}
+
+func newWatchChannel(option *WatchOption) *watchChannel {
+	opt := option.withDefaults()
+	return &watchChannel{
+		option:  opt,
+		dataCh:  make(chan watchResult, opt.BufferSize),
+		closeCh: make(chan struct{}),
+	}
+}
+
+// run polls until the channel is closed. Poll errors are delivered to the
+// consumer after the results of the same poll, if any, and delay the next
+// poll with exponential backoff.
+func (wc *watchChannel) run(poll func() ([]interface{}, error)) {
+	defer close(wc.dataCh)
+
+	var failures uint
+	timer := time.NewTimer(wc.option.PollInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-wc.closeCh:
+			return
+		case <-timer.C:
+		}
+
+		results, err := poll()
+		for _, r := range results {
+			if !wc.deliver(watchResult{data: r}) {
+				return
+			}
+		}
+		if err != nil {
+			failures++
+			if !wc.deliver(watchResult{err: err}) {
+				return
+			}
+		} else {
+			failures = 0
+		}
+		timer.Reset(wc.option.backoff(failures))
+	}
+}
+
+// deliver hands a result to the consumer according to the backpressure
+// policy. It returns false if the watcher has to stop.
+func (wc *watchChannel) deliver(r watchResult) bool {
+	select {
+	case wc.dataCh <- r:
+		return true
+	case <-wc.closeCh:
+		return false
+	default:
+	}
+
+	switch wc.option.Backpressure {
+	case BackpressureDropOldest:
+		for {
+			select {
+			case wc.dataCh <- r:
+				return true
+			case <-wc.closeCh:
+				return false
+			default:
+			}
+			select {
+			case <-wc.dataCh:
+			default:
+			}
+		}
+	case BackpressureError:
+		wc.fail(ErrWatchOverflow)
+		return false
+	default:
+		select {
+		case wc.dataCh <- r:
+			return true
+		case <-wc.closeCh:
+			return false
+		}
+	}
+}
+
+// fail stops the watcher, Next returns err once the buffer is drained.
+func (wc *watchChannel) fail(err error) {
+	wc.lock.Lock()
+	if wc.err == nil {
+		wc.err = err
+	}
+	wc.lock.Unlock()
+	wc.Close()
+}
+
+// Next returns the next item, or the error of a failed poll. Once the channel
+// is closed and drained, it returns ErrChannelClosed, or ErrWatchOverflow if
+// the watcher stopped because of backpressure.
+func (wc *watchChannel) Next() (interface{}, error) {
+	if r, ok := <-wc.dataCh; ok {
+		return r.data, r.err
+	}
+
+	wc.lock.Lock()
+	defer wc.lock.Unlock()
+	if wc.err != nil {
+		return nil, wc.err
+	}
+	return nil, ErrChannelClosed
+}
+
+func (wc *watchChannel) Close() {
+	wc.closeOnce.Do(func() {
+		close(wc.closeCh)
+	})
+}
+
+// watcherSet tracks the watch channels of a filter so they can be closed
+// together.
+type watcherSet struct {
+	lock     sync.Mutex
+	watchers []*watchChannel
+}
+
+func (ws *watcherSet) add(wc *watchChannel) {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+
+	// Forget watchers the consumer has closed already.
+	watchers := ws.watchers[:0]
+	for _, w := range ws.watchers {
+		select {
+		case <-w.closeCh:
+		default:
+			watchers = append(watchers, w)
+		}
+	}
+	ws.watchers = append(watchers, wc)
+}
+
+func (ws *watcherSet) closeAll() {
+	ws.lock.Lock()
+	watchers := ws.watchers
+	ws.watchers = nil
+	ws.lock.Unlock()
+
+	for _, wc := range watchers {
+		wc.Close()
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type WatchTestSuite struct {
+	suite.Suite
+}
+
+func (suite *WatchTestSuite) Test_Defaults() {
+	var option *WatchOption
+	opt := option.withDefaults()
+	assert.Equal(suite.T(), pollInterval, opt.PollInterval, "should be equal")
+	assert.Equal(suite.T(), dataBufferSize, opt.BufferSize, "should be equal")
+	assert.Equal(suite.T(), maxPollBackoff, opt.MaxBackoff, "should be equal")
+}
+
+func (suite *WatchTestSuite) Test_Backoff() {
+	opt := (&WatchOption{PollInterval: time.Second, MaxBackoff: 5 * time.Second}).withDefaults()
+	assert.Equal(suite.T(), time.Second, opt.backoff(0), "should be equal")
+	assert.Equal(suite.T(), 2*time.Second, opt.backoff(1), "should be equal")
+	assert.Equal(suite.T(), 4*time.Second, opt.backoff(2), "should be equal")
+	assert.Equal(suite.T(), 5*time.Second, opt.backoff(3), "should be equal")
+	assert.Equal(suite.T(), 5*time.Second, opt.backoff(100), "should be equal")
+}
+
+func (suite *WatchTestSuite) Test_Errors() {
+	failure := errors.New("node is down")
+	var lock sync.Mutex
+	var polls []time.Time
+	wc := newWatchChannel(&WatchOption{PollInterval: 5 * time.Millisecond, MaxBackoff: time.Second})
+	go wc.run(func() ([]interface{}, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		polls = append(polls, time.Now())
+		if len(polls) <= 3 {
+			return nil, failure
+		}
+		return []interface{}{len(polls)}, nil
+	})
+
+	for i := 0; i < 3; i++ {
+		data, err := wc.Next()
+		assert.Nil(suite.T(), data, "should be nil")
+		assert.Equal(suite.T(), failure, err, "should be equal")
+	}
+	data, err := wc.Next()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), 4, data, "should be equal")
+	wc.Close()
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.True(suite.T(), polls[3].Sub(polls[2]) >= 4*polls[1].Sub(polls[0])/2, "should back off")
+}
+
+func (suite *WatchTestSuite) Test_PartialResults() {
+	failure := errors.New("node is down")
+	wc := newWatchChannel(&WatchOption{PollInterval: time.Millisecond})
+	defer wc.Close()
+	go wc.run(func() ([]interface{}, error) {
+		return []interface{}{1}, failure
+	})
+
+	data, err := wc.Next()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), 1, data, "should be equal")
+	_, err = wc.Next()
+	assert.Equal(suite.T(), failure, err, "should be equal")
+}
+
+func (suite *WatchTestSuite) Test_BackpressureDropOldest() {
+	wc := newWatchChannel(&WatchOption{
+		PollInterval: time.Millisecond,
+		BufferSize:   2,
+		Backpressure: BackpressureDropOldest,
+	})
+	done := make(chan struct{})
+	polls := 0
+	go wc.run(func() ([]interface{}, error) {
+		polls++
+		switch polls {
+		case 1:
+			return []interface{}{1, 2, 3, 4}, nil
+		case 2:
+			close(done)
+		}
+		return nil, nil
+	})
+
+	<-done
+	data, err := wc.Next()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), 3, data, "should be equal")
+	data, err = wc.Next()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), 4, data, "should be equal")
+	wc.Close()
+	_, err = wc.Next()
+	assert.Equal(suite.T(), ErrChannelClosed, err, "should be equal")
+}
+
+func (suite *WatchTestSuite) Test_BackpressureError() {
+	wc := newWatchChannel(&WatchOption{
+		PollInterval: time.Millisecond,
+		BufferSize:   2,
+		Backpressure: BackpressureError,
+	})
+	go wc.run(func() ([]interface{}, error) {
+		return []interface{}{1, 2, 3}, nil
+	})
+
+	<-wc.closeCh
+	data, err := wc.Next()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), 1, data, "should be equal")
+	data, err = wc.Next()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), 2, data, "should be equal")
+	_, err = wc.Next()
+	assert.Equal(suite.T(), ErrWatchOverflow, err, "should be equal")
+}
+
+func (suite *WatchTestSuite) Test_BackpressureBlock() {
+	wc := newWatchChannel(&WatchOption{
+		PollInterval: time.Millisecond,
+		BufferSize:   1,
+	})
+	go wc.run(func() ([]interface{}, error) {
+		return []interface{}{1, 2}, nil
+	})
+
+	for i := 0; i < 6; i++ {
+		data, err := wc.Next()
+		assert.NoError(suite.T(), err, "Should be no error")
+		assert.Equal(suite.T(), i%2+1, data, "should be equal")
+	}
+
+	// Close must not hang while the poller is blocked on a full buffer.
+	wc.Close()
+	for {
+		if _, err := wc.Next(); err != nil {
+			assert.Equal(suite.T(), ErrChannelClosed, err, "should be equal")
+			break
+		}
+	}
+}
+
+func Test_WatchTestSuite(t *testing.T) {
+	suite.Run(t, new(WatchTestSuite))
+}
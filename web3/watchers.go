@@ -0,0 +1,313 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/alanchchen/web3go/common"
+)
+
+// errorBuffer is the number of poll errors kept for the Errors channel of a
+// watcher.
+const errorBuffer = 16
+
+// LogWatcher delivers the logs of a log filter. Logs can be received either
+// with Next or from the Logs channel, along with poll errors from the Errors
+// channel. Errors are buffered, when nobody receives them the oldest are
+// dropped, so they never hold up the logs.
+type LogWatcher interface {
+	Next() (common.Log, error)
+	Logs() <-chan common.Log
+	Errors() <-chan error
+	Close()
+}
+
+// HashWatcher delivers the hashes of new blocks or pending transactions.
+type HashWatcher interface {
+	Next() (common.Hash, error)
+	Hashes() <-chan common.Hash
+	Errors() <-chan error
+	Close()
+}
+
+// HeaderWatcher delivers new blocks, without their transactions.
+type HeaderWatcher interface {
+	Next() (*common.Block, error)
+	Headers() <-chan *common.Block
+	Errors() <-chan error
+	Close()
+}
+
+// WatchLogs installs a log filter and watches it. Closing the watcher
+// uninstalls the filter.
+func (eth *EthAPI) WatchLogs(option *FilterOption, watch *WatchOption) (LogWatcher, error) {
+	filter, err := eth.NewFilter(option)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &logWatcher{ch: make(chan common.Log)}
+	w.typedWatch = newTypedWatch(eth, filter, filter.WatchWithOption(watch), decodeLog, w.ch)
+	return w, nil
+}
+
+// WatchBlocks installs a block filter and delivers the hashes of new blocks.
+func (eth *EthAPI) WatchBlocks(watch *WatchOption) (HashWatcher, error) {
+	filter, err := eth.NewBlockFilter()
+	if err != nil {
+		return nil, err
+	}
+	return newHashWatcher(eth, filter, watch), nil
+}
+
+// WatchBlockHeaders installs a block filter and delivers new blocks. Each
+// block is fetched by hash, a failed fetch is reported as an error and the
+// block is skipped.
+func (eth *EthAPI) WatchBlockHeaders(watch *WatchOption) (HeaderWatcher, error) {
+	filter, err := eth.NewBlockFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	decode := func(data interface{}) (interface{}, error) {
+		hash, err := decodeHash(data)
+		if err != nil {
+			return nil, err
+		}
+		return eth.GetBlockByHash(hash.(common.Hash), false)
+	}
+	w := &headerWatcher{ch: make(chan *common.Block)}
+	w.typedWatch = newTypedWatch(eth, filter, filter.WatchWithOption(watch), decode, w.ch)
+	return w, nil
+}
+
+// WatchPendingTransactions installs a pending transaction filter and delivers
+// the hashes of new pending transactions.
+func (eth *EthAPI) WatchPendingTransactions(watch *WatchOption) (HashWatcher, error) {
+	filter, err := eth.NewPendingTransactionFilter()
+	if err != nil {
+		return nil, err
+	}
+	return newHashWatcher(eth, filter, watch), nil
+}
+
+// typedWatch decodes the items of a watch channel. Items are forwarded to
+// channels once the consumer asks for them.
+type typedWatch struct {
+	wc     WatchChannel
+	decode func(interface{}) (interface{}, error)
+	// values is the typed channel the decoded items are sent to.
+	values reflect.Value
+	stop   func()
+
+	errCh     chan error
+	done      chan struct{}
+	startOnce sync.Once
+	closeOnce sync.Once
+}
+
+// newTypedWatch decodes the items of wc, a watch channel of filter, and
+// forwards them to values, a channel of the decoded type. Closing the watcher
+// uninstalls filter.
+func newTypedWatch(eth *EthAPI, filter Filter, wc WatchChannel, decode func(interface{}) (interface{}, error), values interface{}) *typedWatch {
+	return &typedWatch{
+		wc:     wc,
+		decode: decode,
+		values: reflect.ValueOf(values),
+		stop:   func() { eth.UninstallFilter(filter) },
+		errCh:  make(chan error, errorBuffer),
+		done:   make(chan struct{}),
+	}
+}
+
+func (w *typedWatch) next() (interface{}, error) {
+	data, err := w.wc.Next()
+	if err != nil {
+		return nil, err
+	}
+	return w.decode(data)
+}
+
+func (w *typedWatch) start() {
+	w.startOnce.Do(func() {
+		go w.forward()
+	})
+}
+
+// forward moves items to the channels until the watch channel is closed.
+func (w *typedWatch) forward() {
+	defer w.values.Close()
+	defer close(w.errCh)
+
+	for {
+		v, err := w.next()
+		if err == ErrChannelClosed {
+			return
+		}
+		if err != nil {
+			w.report(err)
+			if err == ErrWatchOverflow {
+				return
+			}
+			continue
+		}
+		if !w.send(v) {
+			return
+		}
+	}
+}
+
+// send delivers a decoded item to the values channel, it returns false once
+// the watcher is closed.
+func (w *typedWatch) send(v interface{}) bool {
+	value := reflect.ValueOf(v)
+	if v == nil {
+		value = reflect.Zero(w.values.Type().Elem())
+	}
+	chosen, _, _ := reflect.Select([]reflect.SelectCase{
+		{Dir: reflect.SelectSend, Chan: w.values, Send: value},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(w.done)},
+	})
+	return chosen == 0
+}
+
+// report queues err on the Errors channel without blocking. When the buffer
+// is full the oldest error is dropped, so the latest one is always kept.
+func (w *typedWatch) report(err error) {
+	for {
+		select {
+		case w.errCh <- err:
+			return
+		default:
+		}
+		select {
+		case <-w.errCh:
+		default:
+		}
+	}
+}
+
+// Errors returns a channel of poll errors. It is closed with the value
+// channel when the watcher stops. Up to 16 errors are buffered, older ones
+// are dropped when nobody receives them.
+func (w *typedWatch) Errors() <-chan error {
+	w.start()
+	return w.errCh
+}
+
+// Close stops the watcher and uninstalls its filter.
+func (w *typedWatch) Close() {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		w.wc.Close()
+		w.stop()
+	})
+}
+
+type logWatcher struct {
+	*typedWatch
+	ch chan common.Log
+}
+
+func (w *logWatcher) Next() (common.Log, error) {
+	v, err := w.next()
+	if err != nil {
+		return common.Log{}, err
+	}
+	return v.(common.Log), nil
+}
+
+// Logs returns a channel of logs, closed when the watcher stops.
+func (w *logWatcher) Logs() <-chan common.Log {
+	w.start()
+	return w.ch
+}
+
+type hashWatcher struct {
+	*typedWatch
+	ch chan common.Hash
+}
+
+func newHashWatcher(eth *EthAPI, filter Filter, watch *WatchOption) HashWatcher {
+	w := &hashWatcher{ch: make(chan common.Hash)}
+	w.typedWatch = newTypedWatch(eth, filter, filter.WatchWithOption(watch), decodeHash, w.ch)
+	return w
+}
+
+func (w *hashWatcher) Next() (common.Hash, error) {
+	v, err := w.next()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return v.(common.Hash), nil
+}
+
+// Hashes returns a channel of hashes, closed when the watcher stops.
+func (w *hashWatcher) Hashes() <-chan common.Hash {
+	w.start()
+	return w.ch
+}
+
+type headerWatcher struct {
+	*typedWatch
+	ch chan *common.Block
+}
+
+func (w *headerWatcher) Next() (*common.Block, error) {
+	v, err := w.next()
+	if err != nil {
+		return nil, err
+	}
+	return v.(*common.Block), nil
+}
+
+// Headers returns a channel of blocks, closed when the watcher stops.
+func (w *headerWatcher) Headers() <-chan *common.Block {
+	w.start()
+	return w.ch
+}
+
+func decodeLog(data interface{}) (interface{}, error) {
+	logs, err := toLogs([]interface{}{data})
+	if err != nil {
+		return nil, err
+	}
+	return logs[0], nil
+}
+
+func decodeHash(data interface{}) (interface{}, error) {
+	s, ok := data.(string)
+	if !ok {
+		return nil, common.ErrInvalidHex
+	}
+	return common.ParseHash(s)
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package web3
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alanchchen/web3go/common"
+	"github.com/alanchchen/web3go/provider"
+	"github.com/alanchchen/web3go/rpc"
+	"github.com/alanchchen/web3go/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// flakyProvider fails the first polls of a filter.
+type flakyProvider struct {
+	provider.Provider
+
+	lock  sync.Mutex
+	fails int
+}
+
+func (p *flakyProvider) Send(request rpc.Request) (rpc.Response, error) {
+	p.lock.Lock()
+	fail := request.Get("method").(string) == "eth_getFilterChanges" && p.fails > 0
+	if fail {
+		p.fails--
+	}
+	p.lock.Unlock()
+
+	if fail {
+		return nil, errors.New("Connection refused")
+	}
+	return p.Provider.Send(request)
+}
+
+type WatchersTestSuite struct {
+	suite.Suite
+	provider *chainProvider
+	eth      Eth
+	watch    *WatchOption
+}
+
+func (suite *WatchersTestSuite) Test_WatchLogs() {
+	eth := NewWeb3(test.NewMockHTTPProvider()).Eth
+	watcher, err := eth.WatchLogs(&FilterOption{}, suite.watch)
+	assert.NoError(suite.T(), err, "Should be no error")
+	defer watcher.Close()
+
+	log, err := watcher.Next()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), big.NewInt(0x1b4), log.BlockNumber, "Should be equal")
+	assert.Equal(suite.T(), uint64(1), log.LogIndex, "Should be equal")
+
+	select {
+	case log := <-watcher.Logs():
+		assert.Equal(suite.T(), big.NewInt(0x1b4), log.BlockNumber, "Should be equal")
+	case err := <-watcher.Errors():
+		assert.NoError(suite.T(), err, "Should be no error")
+	case <-time.After(time.Second):
+		suite.T().Error("Should receive a log")
+	}
+}
+
+func (suite *WatchersTestSuite) Test_WatchBlocks() {
+	watcher, err := suite.eth.WatchBlocks(suite.watch)
+	assert.NoError(suite.T(), err, "Should be no error")
+	defer watcher.Close()
+
+	select {
+	case hash := <-watcher.Hashes():
+		assert.Equal(suite.T(), common.NewHash([]byte{0, 10, 1}), hash, "Should be equal")
+	case <-time.After(time.Second):
+		suite.T().Error("Should receive a hash")
+	}
+}
+
+func (suite *WatchersTestSuite) Test_WatchBlockHeaders() {
+	watcher, err := suite.eth.WatchBlockHeaders(suite.watch)
+	assert.NoError(suite.T(), err, "Should be no error")
+	defer watcher.Close()
+
+	// Headers are decoded from the hex encoding of nodes.
+	block, err := watcher.Next()
+	if assert.NoError(suite.T(), err, "Should be no error") {
+		assert.Equal(suite.T(), int64(10), block.Number.Int64(), "Should be equal")
+		assert.Equal(suite.T(), common.NewHash([]byte{0, 10, 1}), block.Hash, "Should be equal")
+		assert.Equal(suite.T(), common.NewHash([]byte{0, 9, 1}), block.ParentHash, "Should be equal")
+	}
+
+	suite.provider.extend(1, 0)
+	select {
+	case block := <-watcher.Headers():
+		assert.Equal(suite.T(), int64(11), block.Number.Int64(), "Should be equal")
+		assert.Equal(suite.T(), common.NewHash([]byte{0, 10, 1}), block.ParentHash, "Should be equal")
+	case err := <-watcher.Errors():
+		assert.NoError(suite.T(), err, "Should be no error")
+	case <-time.After(time.Second):
+		suite.T().Error("Should receive a block")
+	}
+}
+
+func (suite *WatchersTestSuite) Test_WatchPendingTransactions() {
+	watcher, err := suite.eth.WatchPendingTransactions(suite.watch)
+	assert.NoError(suite.T(), err, "Should be no error")
+	defer watcher.Close()
+
+	hash, err := watcher.Next()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), common.NewHash([]byte{0, 10, 1}), hash, "Should be equal")
+}
+
+func (suite *WatchersTestSuite) Test_DecodeError() {
+	// The mock answers every filter with logs.
+	eth := NewWeb3(test.NewMockHTTPProvider()).Eth
+	watcher, err := eth.WatchBlocks(suite.watch)
+	assert.NoError(suite.T(), err, "Should be no error")
+	defer watcher.Close()
+
+	select {
+	case err := <-watcher.Errors():
+		assert.Equal(suite.T(), common.ErrInvalidHex, err, "Should be equal")
+	case <-watcher.Hashes():
+		suite.T().Error("Should not receive a hash")
+	case <-time.After(time.Second):
+		suite.T().Error("Should receive an error")
+	}
+}
+
+func (suite *WatchersTestSuite) Test_UnreadErrors() {
+	eth := NewWeb3(&flakyProvider{Provider: suite.provider, fails: errorBuffer + 4}).Eth
+	watcher, err := eth.WatchBlocks(&WatchOption{PollInterval: time.Millisecond, MaxBackoff: time.Millisecond})
+	assert.NoError(suite.T(), err, "Should be no error")
+	defer watcher.Close()
+
+	// Errors are never received, they must not hold up the hashes.
+	select {
+	case hash := <-watcher.Hashes():
+		assert.Equal(suite.T(), common.NewHash([]byte{0, 10, 1}), hash, "Should be equal")
+	case <-time.After(time.Second):
+		suite.T().Fatal("Should receive a hash")
+	}
+	assert.Len(suite.T(), watcher.Errors(), errorBuffer, "Should keep the latest errors")
+}
+
+func (suite *WatchersTestSuite) Test_Close() {
+	watcher, err := suite.eth.WatchBlocks(suite.watch)
+	assert.NoError(suite.T(), err, "Should be no error")
+	hashes := watcher.Hashes()
+	watcher.Close()
+	watcher.Close()
+
+	for range hashes {
+	}
+	_, ok := <-watcher.Errors()
+	assert.False(suite.T(), ok, "Should be closed")
+	assert.Equal(suite.T(), 1, suite.provider.uninstall, "Should be equal")
+}
+
+func (suite *WatchersTestSuite) SetupTest() {
+	suite.provider = newChainProvider(10)
+	suite.eth = NewWeb3(suite.provider).Eth
+	suite.watch = &WatchOption{PollInterval: time.Millisecond}
+}
+
+func Test_WatchersTestSuite(t *testing.T) {
+	suite.Run(t, new(WatchersTestSuite))
+}
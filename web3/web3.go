@@ -35,13 +35,12 @@ import (
 	"fmt"
 	"math"
 	"math/big"
-	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/alanchchen/web3go/common"
 	"github.com/alanchchen/web3go/provider"
-	"github.com/tonnerre/golang-go.crypto/sha3"
 )
 
 var (
@@ -81,42 +80,62 @@ var (
 // Web3 Standard interface
 // See https://github.com/ethereum/wiki/wiki/JavaScript-API#web3js-api-reference
 type Web3 struct {
-	provider       provider.Provider
 	requestManager *requestManager
 	Eth            Eth
 	Net            Net
+
+	capabilitiesLock sync.Mutex
+	capabilities     *Capabilities
 }
 
 // NewWeb3 creates a new web3 object.
 func NewWeb3(provider provider.Provider) *Web3 {
 	requestManager := newRequestManager(provider)
 	return &Web3{
-		provider:       provider,
 		requestManager: requestManager,
 		Eth:            newEthAPI(requestManager),
 		Net:            newNetAPI(requestManager)}
 }
 
+// ClientVersion returns the current client version.
+func (web3 *Web3) ClientVersion() (string, error) {
+	req := web3.requestManager.newRequest("web3_clientVersion")
+	resp, err := web3.requestManager.send(req)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.Error() != nil {
+		return "", resp.Error()
+	}
+
+	return resp.Get("result").(string), nil
+}
+
 // IsConnected checks if a connection to a node exists.
 func (web3 *Web3) IsConnected() bool {
-	return true
+	return web3.requestManager.getProvider().IsConnected()
 }
 
-// SetProvider sets provider.
+// SetProvider sets provider. It is safe to call while other goroutines use
+// Eth and Net: requests already sent complete against the old provider.
+// Filters are node-local, so filters installed through the old provider are
+// not valid on the new one.
 func (web3 *Web3) SetProvider(provider provider.Provider) {
-	web3.provider = provider
+	web3.requestManager.setProvider(provider)
+	web3.resetCapabilities()
 }
 
 // CurrentProvider returns the current provider.
 func (web3 *Web3) CurrentProvider() provider.Provider {
-	return web3.provider
+	return web3.requestManager.getProvider()
 }
 
 // Reset state of web3. Resets everything except manager. Uninstalls all
 // filters. Stops polling. If keepSyncing is true, it will uninstall all
-// filters, but will keep the web3.eth.IsSyncing() polls.
+// filters, but will keep the Eth.WatchSyncing() polls.
 func (web3 *Web3) Reset(keepSyncing bool) {
-
+	web3.requestManager.reset(keepSyncing)
 }
 
 // Sha3 returns Keccak-256 (not the standardized SHA3-256) of the given data.
@@ -242,10 +261,20 @@ func (web3 *Web3) FromDecimal(value interface{}) string {
 // - mether
 // - gether
 // - tether
+//
+// FromWei keeps fractional digits, e.g. "1500000000000000000" is "1.5" ether.
+// It returns an empty string for malformed input or an unknown unit, use
+// FormatUnit to get the error instead.
 func (web3 *Web3) FromWei(number string, unit string) string {
-	num := web3.ToBigNumber(number)
-	returnValue := num.Quo(num, web3.getValueOfUnit(unit))
-	return returnValue.Num().String()
+	num := web3.parseNumber(number)
+	if num == nil || !num.IsInt() {
+		return ""
+	}
+	result, err := FormatUnit(num.Num(), unit, nil)
+	if err != nil {
+		return ""
+	}
+	return result
 }
 
 // ToWei takes a number of a unit and converts it to wei.
@@ -263,10 +292,27 @@ func (web3 *Web3) FromWei(number string, unit string) string {
 // - mether
 // - gether
 // - tether
+//
+// ToWei accepts fractional input such as "1.5". It returns an empty string for
+// malformed input, an unknown unit, or an amount smaller than one wei, use
+// ParseUnit to get the error instead.
 func (web3 *Web3) ToWei(number interface{}, unit string) string {
-	num := web3.ToBigNumber(number)
-	returnValue := num.Mul(num, web3.getValueOfUnit(unit))
-	return returnValue.Num().String()
+	num := web3.parseNumber(number)
+	if num == nil {
+		return ""
+	}
+	if isNoether(unit) {
+		return "0"
+	}
+	decimals, err := UnitDecimals(unit)
+	if err != nil {
+		return ""
+	}
+	result, err := ratToAmount(num, decimals)
+	if err != nil {
+		return ""
+	}
+	return result.String()
 }
 
 // ToBigNumber takes an input and transforms it into an *big.Rat.
@@ -287,72 +333,45 @@ func (web3 *Web3) ToBigNumber(value interface{}) (result *big.Rat) {
 
 		if strings.Index(v, "0x") == 0 || strings.Index(v, "-0x") == 0 {
 			i.SetString(strings.Replace(v, "0x", "", -1), 16)
-		} else {
-			i.SetString(v, 10)
+			result.SetInt(i)
+		} else if _, ok := result.SetString(v); !ok {
+			result.SetInt64(0)
 		}
-		result.SetInt(i)
 		return result
 	}
 	return result
 }
 
-// IsAddress checks if the given string is an address.
-func (web3 *Web3) IsAddress(address string) bool {
-	smallCapsMatcher := regexp.MustCompile("^(0x)?[0-9a-f]{40}$")
-	smallCapsMatched := smallCapsMatcher.MatchString(address)
-	allCapsMatcher := regexp.MustCompile("^(0x)?[0-9A-F]{40}$")
-	allCapsMatched := allCapsMatcher.MatchString(address)
-	if smallCapsMatched || allCapsMatched {
-		return true
+// parseNumber is like ToBigNumber, but returns nil for malformed strings
+// instead of 0. Strings are either hex with a 0x prefix or plain decimals.
+func (web3 *Web3) parseNumber(value interface{}) *big.Rat {
+	s, ok := value.(string)
+	if !ok {
+		return web3.ToBigNumber(value)
 	}
-	return web3.isChecksumAddress(address)
-}
-
-func (web3 *Web3) isChecksumAddress(address string) bool {
-	addr := strings.Replace(address, "0x", "", -1)
-	addressHash := web3.Sha3(strings.ToLower(addr), "")
-
-	for i := 0; i < 40; i++ {
-		d, err := strconv.ParseInt(string(addressHash[i]), 16, 32)
-		if err != nil {
-			return false
-		}
-
-		if d > 7 && strings.ToUpper(string(address[i])) == string(address[i]) ||
-			d <= 7 && strings.ToLower(string(address[i])) == string(address[i]) {
-			return false
-		}
+	if hexPattern.MatchString(s) {
+		return web3.ToBigNumber(s)
 	}
-	return true
+	return parseDecimal(s)
 }
 
-func (web3 *Web3) sha3Hash(data ...[]byte) []byte {
-	d := sha3.NewKeccak256()
-	for _, b := range data {
-		d.Write(b)
-	}
-	return d.Sum(nil)
+// IsAddress checks if the given string is an address. All-lowercase and
+// all-uppercase input is accepted as is, mixed-case input must carry a valid
+// EIP-55 checksum.
+func (web3 *Web3) IsAddress(address string) bool {
+	_, err := common.ParseAddress(address)
+	return err == nil
 }
 
-func (web3 *Web3) getValueOfUnit(unit string) *big.Rat {
-	u := strings.TrimSpace(unit)
-	if u != "" {
-		u = strings.ToLower(u)
-	} else {
-		u = "ether"
-	}
-
-	if unitValue, ok := unitMap[u]; ok {
-		value := new(big.Int)
-		value.SetString(unitValue, 10)
-		returnValue := new(big.Rat)
-		returnValue.SetInt(value)
-		return returnValue
+// ToChecksumAddress converts an address to its EIP-55 checksum encoding.
+func (web3 *Web3) ToChecksumAddress(address string) (string, error) {
+	addr, err := common.ParseAddress(strings.ToLower(address))
+	if err != nil {
+		return "", err
 	}
+	return addr.Hex(), nil
+}
 
-	keys := make([]string, 0, len(unitMap))
-	for k := range unitMap {
-		keys = append(keys, k)
-	}
-	panic(fmt.Sprintf("This unit doesn\"t exists, please use the one of the following units, %v", keys))
+func (web3 *Web3) sha3Hash(data ...[]byte) []byte {
+	return common.Keccak256(data...)
 }
@@ -31,13 +31,30 @@ package web3
 
 import (
 	"math/big"
+	"sync"
 	"testing"
 
+	"github.com/alanchchen/web3go/provider"
+	"github.com/alanchchen/web3go/rpc"
 	"github.com/alanchchen/web3go/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
 
+// gatewayProvider answers the blocked methods with an HTTP error status, like
+// a gateway that only allows some methods through.
+type gatewayProvider struct {
+	provider.Provider
+	blocked map[string]bool
+}
+
+func (p *gatewayProvider) Send(request rpc.Request) (rpc.Response, error) {
+	if p.blocked[request.Get("method").(string)] {
+		return nil, &provider.HTTPError{StatusCode: 403, Body: "Method not allowed"}
+	}
+	return p.Provider.Send(request)
+}
+
 type Web3TestSuite struct {
 	suite.Suite
 	web3 *Web3
@@ -48,6 +65,128 @@ func (suite *Web3TestSuite) Test_IsConnected() {
 	assert.Equal(suite.T(), web3.IsConnected(), true, "should be true")
 }
 
+func (suite *Web3TestSuite) Test_SetProvider() {
+	web3 := suite.web3
+	c, err := web3.Capabilities()
+	assert.NoError(suite.T(), err, "Should be no error")
+
+	provider := test.NewMockHTTPProvider()
+	web3.SetProvider(provider)
+	assert.True(suite.T(), provider == web3.CurrentProvider(), "should be the new provider")
+	assert.True(suite.T(), web3.IsConnected(), "should be true")
+
+	probed, err := web3.Capabilities()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.False(suite.T(), c == probed, "should be probed again")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				number, err := web3.Eth.BlockNumber()
+				assert.NoError(suite.T(), err, "Should be no error")
+				assert.EqualValues(suite.T(), big.NewInt(0x4b7), number, "should be equal")
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		web3.SetProvider(test.NewMockHTTPProvider())
+	}
+	wg.Wait()
+}
+
+type fakePoll struct {
+	isSyncing bool
+	stopped   bool
+}
+
+func (p *fakePoll) stop() {
+	p.stopped = true
+}
+
+func (p *fakePoll) syncing() bool {
+	return p.isSyncing
+}
+
+func (suite *Web3TestSuite) Test_Reset() {
+	web3 := suite.web3
+	filter, err := web3.Eth.NewBlockFilter()
+	assert.NoError(suite.T(), err, "Should be no error")
+	watcher := filter.Watch()
+
+	syncingPoll := &fakePoll{isSyncing: true}
+	web3.requestManager.startPolling(syncingPoll)
+
+	web3.Reset(true)
+	for {
+		if _, err := watcher.Next(); err != nil {
+			assert.Equal(suite.T(), ErrChannelClosed, err, "should be equal")
+			break
+		}
+	}
+	assert.False(suite.T(), syncingPoll.stopped, "should keep syncing polls")
+	assert.Len(suite.T(), web3.requestManager.polls, 1, "should only keep syncing polls")
+
+	web3.Reset(false)
+	assert.True(suite.T(), syncingPoll.stopped, "should stop syncing polls")
+	assert.Empty(suite.T(), web3.requestManager.polls, "should stop all polls")
+}
+
+func (suite *Web3TestSuite) Test_ClientVersion() {
+	web3 := suite.web3
+	version, err := web3.ClientVersion()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), "Mock/v0.1.0/linux/go1.6", version, "should be equal")
+}
+
+func (suite *Web3TestSuite) Test_Capabilities() {
+	web3 := suite.web3
+	c, err := web3.Capabilities()
+	assert.NoError(suite.T(), err, "Should be no error")
+	if assert.NotNil(suite.T(), c, "Should not be nil") {
+		assert.Equal(suite.T(), "Mock/v0.1.0/linux/go1.6", c.ClientVersion, "should be equal")
+		assert.True(suite.T(), c.HasNamespace("eth"), "should be true")
+		assert.False(suite.T(), c.HasNamespace("debug"), "should be false")
+		assert.True(suite.T(), c.Supports("eth_chainId"), "should be true")
+		assert.True(suite.T(), c.Supports("eth_getLogs"), "should be true")
+		assert.False(suite.T(), c.Supports("txpool_status"), "should be false")
+		assert.False(suite.T(), c.Supports("txpool_content"), "should be false")
+		assert.NotContains(suite.T(), c.Methods, "txpool_content", "should not be probed")
+		assert.False(suite.T(), c.Subscriptions, "should be false")
+		assert.True(suite.T(), c.Batch, "should be true")
+	}
+
+	cached, err := web3.Capabilities()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.True(suite.T(), c == cached, "should be cached")
+
+	probed, err := web3.ProbeCapabilities()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.False(suite.T(), c == probed, "should be probed again")
+}
+
+func (suite *Web3TestSuite) Test_CapabilitiesBehindGateway() {
+	web3 := NewWeb3(&gatewayProvider{
+		Provider: test.NewMockHTTPProvider(),
+		blocked:  map[string]bool{"rpc_modules": true, "eth_chainId": true},
+	})
+	c, err := web3.Capabilities()
+	assert.NoError(suite.T(), err, "Should be no error")
+	if assert.NotNil(suite.T(), c, "Should not be nil") {
+		assert.False(suite.T(), c.Supports("eth_chainId"), "should be false")
+		assert.Contains(suite.T(), c.Methods, "txpool_status", "should be probed")
+	}
+
+	web3 = NewWeb3(&gatewayProvider{
+		Provider: test.NewMockHTTPProvider(),
+		blocked:  map[string]bool{"web3_clientVersion": true},
+	})
+	_, err = web3.Capabilities()
+	assert.Error(suite.T(), err, "Should be error")
+}
+
 func (suite *Web3TestSuite) Test_Sha3() {
 	web3 := suite.web3
 	s := "Some string to be hashed"
@@ -124,6 +263,12 @@ func (suite *Web3TestSuite) Test_FromWei() {
 	assert.Equal(suite.T(), "123", web3.FromWei(s, "ether"), "should be equal")
 	s = "1000000000000000000000000000000"
 	assert.Equal(suite.T(), "1", web3.FromWei(s, "tether"), "should be equal")
+	s = "1500000000000000000"
+	assert.Equal(suite.T(), "1.5", web3.FromWei(s, "ether"), "should be equal")
+	assert.Equal(suite.T(), "", web3.FromWei(s, "unknown"), "should be equal")
+	assert.Equal(suite.T(), "1", web3.FromWei("0xde0b6b3a7640000", "ether"), "should be equal")
+	assert.Equal(suite.T(), "", web3.FromWei("abc", "ether"), "should be equal")
+	assert.Equal(suite.T(), "", web3.FromWei("1e18", "ether"), "should be equal")
 }
 
 func (suite *Web3TestSuite) Test_ToWei() {
@@ -140,6 +285,14 @@ func (suite *Web3TestSuite) Test_ToWei() {
 	assert.Equal(suite.T(), "123000000000000000000", web3.ToWei(s, "ether"), "should be equal")
 	s = "1"
 	assert.Equal(suite.T(), "1000000000000000000000000000000", web3.ToWei(s, "tether"), "should be equal")
+	s = "1.5"
+	assert.Equal(suite.T(), "1500000000000000000", web3.ToWei(s, "ether"), "should be equal")
+	assert.Equal(suite.T(), "", web3.ToWei(s, "wei"), "should be equal")
+	assert.Equal(suite.T(), "", web3.ToWei(s, "unknown"), "should be equal")
+	assert.Equal(suite.T(), "16", web3.ToWei("0x10", "wei"), "should be equal")
+	assert.Equal(suite.T(), "", web3.ToWei("abc", "ether"), "should be equal")
+	assert.Equal(suite.T(), "", web3.ToWei("1_000", "wei"), "should be equal")
+	assert.Equal(suite.T(), "", web3.ToWei("0x", "wei"), "should be equal")
 }
 
 func (suite *Web3TestSuite) Test_ToBigNumber() {
@@ -167,6 +320,15 @@ func (suite *Web3TestSuite) Test_IsAddress() {
 	assert.Equal(suite.T(), false, web3.IsAddress(s), "should be equal")
 }
 
+func (suite *Web3TestSuite) Test_ToChecksumAddress() {
+	web3 := suite.web3
+	s, err := web3.ToChecksumAddress("0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359", s, "should be equal")
+	_, err = web3.ToChecksumAddress("0x1")
+	assert.Error(suite.T(), err, "Should be error")
+}
+
 func (suite *Web3TestSuite) SetupTest() {
 	suite.web3 = NewWeb3(test.NewMockHTTPProvider())
 }